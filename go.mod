@@ -1,3 +1,10 @@
 module github.com/lwmacct/250300-go-mod-mesh
 
 go 1.24.0
+
+require (
+	github.com/creack/pty v1.1.24
+	golang.org/x/sys v0.41.0
+	golang.org/x/text v0.34.0
+	golang.org/x/time v0.14.0
+)
@@ -0,0 +1,35 @@
+//go:build linux
+
+package mesh
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// startWithAffinity 在绑定到 cpus 的线程上启动 cmd。子进程由当前线程 fork 而来并继承其 CPU 亲和性，
+// 从第一条指令起即运行在指定 CPU 上；启动后恢复线程原有的亲和性
+func startWithAffinity(cmd *exec.Cmd, cpus []int) error {
+	if len(cpus) == 0 {
+		return cmd.Start()
+	}
+	var set unix.CPUSet
+	for _, c := range cpus {
+		set.Set(c)
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	var old unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &old); err != nil {
+		return fmt.Errorf("mesh: cpu affinity: %w", err)
+	}
+	if err := unix.SchedSetaffinity(0, &set); err != nil {
+		return fmt.Errorf("mesh: cpu affinity %v: %w", cpus, err)
+	}
+	defer func() { _ = unix.SchedSetaffinity(0, &old) }()
+	return cmd.Start()
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package mesh
+
+import "os/exec"
+
+// startWithAffinity 在非 Linux 平台上忽略 cpus
+func startWithAffinity(cmd *exec.Cmd, cpus []int) error {
+	return cmd.Start()
+}
@@ -0,0 +1,64 @@
+package mesh
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// RunBatch 以 concurrency 个 worker 并发执行 cmds，全部完成后按输入顺序返回。
+// 未设置 context 的 Ts 会使用 ctx；ctx 取消后尚未开始的命令不再执行，State 为 StateCanceled，Err 包含 ErrCanceled。
+// concurrency 小于 1 时按 1 处理
+func RunBatch(ctx context.Context, cmds []*Ts, concurrency int) []*Ts {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	concurrency = min(concurrency, len(cmds))
+
+	jobs := make(chan *Ts)
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range jobs {
+				if t.ctx == nil {
+					t.WithContext(ctx)
+				}
+				t.Exec()
+			}
+		}()
+	}
+
+	var skipped []*Ts
+dispatch:
+	for i, t := range cmds {
+		select {
+		case jobs <- t:
+		case <-ctx.Done():
+			skipped = cmds[i:]
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	// 未执行的命令标记为取消，避免零值结果被当作成功
+	for _, t := range skipped {
+		t.clearResult()
+		t.err = fmt.Errorf("%w: %w", ErrCanceled, ctx.Err())
+		t.exitCode = -1
+		t.state = StateCanceled
+	}
+	return cmds
+}
+
+// RunSequence 按顺序执行 cmds，遇到第一个失败（非零退出、超时或未能启动）即停止，类似 set -e。
+// 返回已执行的结果（包括失败的那一步）以及描述失败步骤的错误，全部成功时错误为 nil
+func RunSequence(cmds []*Ts) ([]*Ts, error) {
+	for i, t := range cmds {
+		if err := t.Exec().AsError(); err != nil {
+			return cmds[:i+1], fmt.Errorf("mesh: step %d: %w", i+1, err)
+		}
+	}
+	return cmds, nil
+}
@@ -0,0 +1,126 @@
+package mesh
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BreakerState 表示熔断器状态
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // 正常执行
+	BreakerOpen                         // 冷却期内直接返回 ErrCircuitOpen
+	BreakerHalfOpen                     // 冷却期已过，放行一次试探执行
+)
+
+// String 返回熔断器状态的名称
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "halfOpen"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// breaker 记录同一命令的连续失败次数
+type breaker struct {
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+var (
+	breakerMu sync.Mutex
+	breakers  = map[string]*breaker{}
+)
+
+// ResetBreakers 将所有命令的熔断器恢复为关闭状态
+func ResetBreakers() {
+	breakerMu.Lock()
+	defer breakerMu.Unlock()
+	clear(breakers)
+}
+
+// ResetBreaker 将当前命令的熔断器恢复为关闭状态
+func (t *Ts) ResetBreaker() *Ts {
+	breakerMu.Lock()
+	defer breakerMu.Unlock()
+	delete(breakers, t.breakerKey())
+	return t
+}
+
+// BreakerState 返回当前命令的熔断器状态
+func (t *Ts) BreakerState() BreakerState {
+	breakerMu.Lock()
+	defer breakerMu.Unlock()
+	b := breakers[t.breakerKey()]
+	switch {
+	case b == nil || b.openUntil.IsZero():
+		return BreakerClosed
+	case b.probing || !time.Now().Before(b.openUntil):
+		return BreakerHalfOpen
+	default:
+		return BreakerOpen
+	}
+}
+
+// breakerKey 按命令区分熔断器，argv 模式使用完整参数
+func (t *Ts) breakerKey() string {
+	if len(t.Cfg.Args) > 0 {
+		return strings.Join(t.Cfg.Args, "\x00")
+	}
+	return t.Cfg.Cmd
+}
+
+// execBreaker 在熔断打开时直接返回错误，否则执行并按结果更新熔断器。
+// 冷却期过后只放行一次试探执行，成功则关闭熔断，失败则重新进入冷却期
+func (t *Ts) execBreaker(run func() *Ts) *Ts {
+	key := t.breakerKey()
+	breakerMu.Lock()
+	b := breakers[key]
+	if b == nil {
+		b = &breaker{}
+		breakers[key] = b
+	}
+	if !b.openUntil.IsZero() {
+		if wait := time.Until(b.openUntil); wait > 0 || b.probing {
+			breakerMu.Unlock()
+			t.clearResult()
+			t.err = fmt.Errorf("%w: %s after %d consecutive failures, retry in %s",
+				ErrCircuitOpen, t.Cfg.Cmd, b.failures, max(wait, 0).Round(time.Millisecond))
+			t.exitCode = -1
+			t.state = StateNotStarted
+			return t
+		}
+		b.probing = true
+	}
+	breakerMu.Unlock()
+
+	run()
+
+	breakerMu.Lock()
+	defer breakerMu.Unlock()
+	// ResetBreaker 可能在执行期间移除了记录
+	if breakers[key] != b {
+		return t
+	}
+	b.probing = false
+	if t.IsSuccess() {
+		b.failures = 0
+		b.openUntil = time.Time{}
+		return t
+	}
+	b.failures++
+	if b.failures >= t.Cfg.BreakerThreshold {
+		b.openUntil = time.Now().Add(t.Cfg.BreakerCooldown)
+	}
+	return t
+}
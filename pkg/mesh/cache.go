@@ -0,0 +1,136 @@
+package mesh
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"sync"
+	"time"
+)
+
+// cacheEntry 保存一次成功执行的结果快照
+type cacheEntry struct {
+	result  *Ts
+	expires time.Time
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = map[string]cacheEntry{}
+)
+
+// ClearCache 清空所有通过 CacheTTL 缓存的执行结果
+func ClearCache() {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	clear(cache)
+}
+
+// cacheable 判断本次执行能否使用缓存。依赖 stdin 的命令结果不可复用，
+// 逐行回调、输出 Writer 与通道在命中时无法收到输出，同样不使用缓存
+func (t *Ts) cacheable() bool {
+	return t.Cfg.CacheTTL > 0 && !t.Cfg.DryRun && len(t.Cfg.ExtraFiles) == 0 &&
+		t.stdin == nil && len(t.expects) == 0 &&
+		t.onStdoutLine == nil && t.onStderrLine == nil && t.stdoutCh == nil &&
+		t.stdoutW == nil && t.stderrW == nil
+}
+
+// execCached 在缓存有效期内直接复用结果，否则执行并缓存成功的结果
+func (t *Ts) execCached() *Ts {
+	key := t.cacheKey()
+	now := time.Now()
+
+	cacheMu.Lock()
+	e, ok := cache[key]
+	cacheMu.Unlock()
+	if ok && now.Before(e.expires) {
+		// 先清空上次执行留下的 dryRun、run 等状态，避免与缓存的结果混在一起
+		t.clearResult()
+		t.copyResult(e.result)
+		t.cached = true
+		return t
+	}
+
+	t.cached = false
+	t.execRetry()
+	if t.IsSuccess() {
+		snapshot := &Ts{}
+		snapshot.copyResult(t)
+		cacheMu.Lock()
+		for k, e := range cache {
+			if now.After(e.expires) {
+				delete(cache, k)
+			}
+		}
+		cache[key] = cacheEntry{result: snapshot, expires: time.Now().Add(t.Cfg.CacheTTL)}
+		cacheMu.Unlock()
+	}
+	return t
+}
+
+// cacheKey 由影响执行方式以及保存的（经过处理的）输出的配置计算缓存键
+func (t *Ts) cacheKey() string {
+	c := t.Cfg
+	h := sha256.New()
+	writeField(h, c.Cmd)
+	writeField(h, c.Shell)
+	writeField(h, c.FallbackShell)
+	for _, a := range c.ShellArgs {
+		writeField(h, a)
+	}
+	writeField(h, "")
+	writeField(h, c.Dir)
+	writeField(h, c.User)
+	writeField(h, c.Chroot)
+	for _, ns := range c.namespaces() {
+		writeField(h, ns)
+	}
+	writeField(h, "")
+	for _, a := range c.Args {
+		writeField(h, a)
+	}
+	writeField(h, "")
+	for _, kv := range t.baseEnv() {
+		writeField(h, kv)
+	}
+	writeField(h, "")
+	// 执行方式；密码错误的 sudo 不能复用已认证调用的结果，密码只参与哈希，不会出现在键中
+	writeField(h, fmt.Sprint(c.Sudo, c.UsePTY, c.RLimitCPU, c.RLimitAS))
+	writeField(h, c.SudoPassword)
+	// 输出的捕获与处理
+	writeField(h, fmt.Sprint(c.Combine, c.RedirectStderrToStdout, c.Encoding, c.StripANSI, c.KeepWhitespace,
+		c.MaxOutputBytes, c.TruncateMode, c.KillOnOutputLimit))
+	// 是否视为成功决定了结果能否被缓存与复用
+	writeField(h, fmt.Sprint(c.SuccessExitCodes))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeField 以 NUL 分隔写入字段，避免不同字段拼接后产生相同的输入
+func writeField(h hash.Hash, s string) {
+	h.Write([]byte(s))
+	h.Write([]byte{0})
+}
+
+// copyResult 复制 src 的执行结果，不包括配置与运行状态
+func (t *Ts) copyResult(src *Ts) {
+	t.stdout = src.stdout
+	t.stderr = src.stderr
+	t.stdoutRaw = src.stdoutRaw
+	t.stderrRaw = src.stderrRaw
+	t.exitCode = src.exitCode
+	t.duration = src.duration
+	t.startedAt = src.startedAt
+	t.finishedAt = src.finishedAt
+	t.pid = src.pid
+	t.truncated = src.truncated
+	t.dropped = src.dropped
+	t.combined = src.combined
+	t.err = src.err
+	t.state = src.state
+	t.attempts = src.attempts
+	t.shellUsed = src.shellUsed
+	t.limitHit = src.limitHit
+	t.reaped = src.reaped
+	t.invocation = src.invocation
+}
@@ -0,0 +1,156 @@
+package mesh
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// diffContext 是统一 diff 中每个变更块前后保留的上下文行数
+	diffContext = 3
+	// diffMaxEdits 是求最短编辑序列时允许的最大编辑距离，超过时输出整体替换
+	diffMaxEdits = 1000
+)
+
+// DiffOutput 以统一 diff（unified diff）格式比较 a、b 的 Lines，两者相同时返回空串，
+// 适合配置漂移检查与黄金输出测试。文件头使用各自的 Cmd，每个变更块保留 3 行上下文；
+// 差异超过 1000 行时不再求最短编辑序列，输出一个整体替换的变更块
+func DiffOutput(a, b *Ts) string {
+	ops := diffLines(a.Lines(), b.Lines())
+	var changes []int
+	for i, op := range ops {
+		if op.kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", a.Cfg.Cmd, b.Cfg.Cmd)
+	for i := 0; i < len(changes); {
+		// 相邻变更之间的相同行不超过两倍上下文时合并为一个块
+		j := i
+		for j+1 < len(changes) && changes[j+1]-changes[j] <= 2*diffContext+1 {
+			j++
+		}
+		start := max(changes[i]-diffContext, 0)
+		end := min(changes[j]+diffContext+1, len(ops))
+		writeHunk(&sb, ops[start:end])
+		i = j + 1
+	}
+	return sb.String()
+}
+
+// diffOp 是一行编辑操作，kind 为 ' '、'-' 或 '+'，ai、bi 为该行之前 a、b 已消耗的行数
+type diffOp struct {
+	kind   byte
+	line   string
+	ai, bi int
+}
+
+// writeHunk 输出一个变更块，行号从 1 开始，长度为 0 时起始行号取其前一行（与 diff -u 一致）
+func writeHunk(sb *strings.Builder, ops []diffOp) {
+	var alen, blen int
+	for _, op := range ops {
+		if op.kind != '+' {
+			alen++
+		}
+		if op.kind != '-' {
+			blen++
+		}
+	}
+	astart, bstart := ops[0].ai, ops[0].bi
+	if alen > 0 {
+		astart++
+	}
+	if blen > 0 {
+		bstart++
+	}
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", astart, alen, bstart, blen)
+	for _, op := range ops {
+		sb.WriteByte(op.kind)
+		sb.WriteString(op.line)
+		sb.WriteByte('\n')
+	}
+}
+
+// diffLines 使用 Myers 算法计算将 a 变为 b 的最短编辑序列。每一步只保存 v[-d..d]，
+// 内存为 O(D²)；编辑距离 D 超过 diffMaxEdits 时放弃求最短序列，退化为整体替换
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	off := n + m + 1
+	v := make([]int, 2*off+1)
+	var trace [][]int
+search:
+	for d := 0; d <= n+m; d++ {
+		if d > diffMaxEdits {
+			return replaceLines(a, b)
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+		trace = append(trace, slices.Clone(v[off-d:off+d+1]))
+	}
+
+	// 从终点沿 trace 回溯，得到逆序的编辑序列；trace[d-1] 中 k 的下标为 k+d-1
+	var ops []diffOp
+	x, y := n, m
+	for d := len(trace); d > 0; d-- {
+		prev := trace[d-1]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && prev[k-1+d-1] < prev[k+1+d-1]) {
+			prevK = k + 1
+		}
+		prevX := prev[prevK+d-1]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{kind: ' ', line: a[x], ai: x, bi: y})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, diffOp{kind: '+', line: b[y], ai: x, bi: y})
+		} else {
+			x--
+			ops = append(ops, diffOp{kind: '-', line: a[x], ai: x, bi: y})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, diffOp{kind: ' ', line: a[x], ai: x, bi: y})
+	}
+	slices.Reverse(ops)
+	return ops
+}
+
+// replaceLines 返回删除 a 全部行、再添加 b 全部行的编辑序列
+func replaceLines(a, b []string) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for i, line := range a {
+		ops = append(ops, diffOp{kind: '-', line: line, ai: i})
+	}
+	for j, line := range b {
+		ops = append(ops, diffOp{kind: '+', line: line, ai: len(a), bi: j})
+	}
+	return ops
+}
@@ -0,0 +1,42 @@
+package mesh
+
+import (
+	"fmt"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// lookupEncoding 按 WHATWG 名称查找字符集，如 gbk、gb18030、big5、shift_jis；空名称返回 nil
+func lookupEncoding(name string) (encoding.Encoding, error) {
+	if name == "" {
+		return nil, nil
+	}
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown encoding %q", ErrInvalidConfig, name)
+	}
+	return enc, nil
+}
+
+// decodeBytes 将 b 从 enc 转换为 UTF-8，enc 为 nil 或转换失败时原样返回
+func decodeBytes(enc encoding.Encoding, b []byte) []byte {
+	if enc == nil || len(b) == 0 {
+		return b
+	}
+	out, err := enc.NewDecoder().Bytes(b)
+	if err != nil {
+		return b
+	}
+	return out
+}
+
+// decodeLine 包装逐行回调，使其收到解码后的行；GBK 等字符集的多字节序列不含换行符，可按行独立解码
+func decodeLine(enc encoding.Encoding, fn func(string)) func(string) {
+	if enc == nil {
+		return fn
+	}
+	return func(line string) {
+		fn(string(decodeBytes(enc, []byte(line))))
+	}
+}
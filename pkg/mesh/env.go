@@ -0,0 +1,340 @@
+package mesh
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// SecretPatterns 是默认的敏感变量名片段，变量名（忽略大小写）包含其一即视为敏感
+var SecretPatterns = []string{"SECRET", "TOKEN", "PASSWORD", "PASSWD", "API_KEY", "APIKEY", "PRIVATE_KEY", "CREDENTIAL"}
+
+// SetSecretKeys 标记敏感变量，仅影响 Show 等展示输出，传给子进程的值不变
+func (t *Ts) SetSecretKeys(keys ...string) *Ts {
+	t.Cfg.SecretKeys = append(t.Cfg.SecretKeys, keys...)
+	return t
+}
+
+// isSecret 判断变量名是否为敏感变量
+func (t *Ts) isSecret(key string) bool {
+	if slices.Contains(t.Cfg.SecretKeys, key) {
+		return true
+	}
+	upper := strings.ToUpper(key)
+	for _, p := range SecretPatterns {
+		if strings.Contains(upper, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskedEnv 返回敏感变量值被替换为 *** 的环境变量副本
+func (t *Ts) maskedEnv() []string {
+	env := t.baseEnv()
+	masked := make([]string, 0, len(env))
+	for _, kv := range env {
+		k, _, ok := strings.Cut(kv, "=")
+		if ok && t.isSecret(k) {
+			kv = k + "=***"
+		}
+		masked = append(masked, kv)
+	}
+	return masked
+}
+
+// ClearEnv 清空环境变量且不再继承当前进程的环境，之后可通过 SetEnv 逐个添加
+func (t *Ts) ClearEnv() *Ts {
+	t.Cfg.Env = []string{}
+	t.Cfg.NoInheritEnv = true
+	return t
+}
+
+// baseEnv 返回当前生效的环境变量，Env 为 nil 且允许继承时取当前进程环境
+func (t *Ts) baseEnv() []string {
+	if t.Cfg.Env == nil && !t.Cfg.NoInheritEnv {
+		return os.Environ()
+	}
+	return t.Cfg.Env
+}
+
+// environ 返回传给 exec.Cmd 的环境变量，非 nil 的空切片表示不继承任何变量
+func (t *Ts) environ() []string {
+	if t.Cfg.Env == nil && t.Cfg.NoInheritEnv {
+		return []string{}
+	}
+	return t.Cfg.Env
+}
+
+// GetEnvVar 读取单个环境变量，存在重复时以最后一个为准（与 os/exec 一致）
+func (t *Ts) GetEnvVar(key string) (string, bool) {
+	var (
+		value string
+		found bool
+	)
+	for _, kv := range t.baseEnv() {
+		k, v, ok := strings.Cut(kv, "=")
+		if ok && k == key {
+			value, found = v, true
+		}
+	}
+	return value, found
+}
+
+// ExpandCmd 返回按 os.Expand 规则将 $VAR/${VAR} 替换为当前环境变量值的 Cfg.Cmd，
+// 未定义的变量替换为空串。仅用于展示或 argv 模式下自行拆分，不影响实际执行
+func (t *Ts) ExpandCmd() string {
+	return expandEnv(t.Cfg.Cmd, t.baseEnv())
+}
+
+// expandEnv 按 env 展开 s 中的变量
+func expandEnv(s string, env []string) string {
+	vars := envMap(env)
+	return os.Expand(s, func(k string) string { return vars[k] })
+}
+
+// GetEnvMap 以 map 形式返回当前生效的环境变量，存在重复时以最后一个为准（与 os/exec 一致）
+func (t *Ts) GetEnvMap() map[string]string {
+	return envMap(t.baseEnv())
+}
+
+// SetEnvMapReplace 同 SetEnvReplace，与 GetEnvMap 对应
+func (t *Ts) SetEnvMapReplace(m map[string]string) *Ts {
+	return t.SetEnvReplace(m)
+}
+
+// envMap 将 KEY=VALUE 切片解析为 map，存在重复时以最后一个为准
+func envMap(env []string) map[string]string {
+	vars := make(map[string]string, len(env))
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			vars[k] = v
+		}
+	}
+	return vars
+}
+
+// SetEnvFromStruct 按字段的 env 标签将结构体（或其指针）转换为环境变量并通过 SetEnv 合并。
+// 没有 env 标签或标签为 "-" 的字段会被忽略，带 ",omitempty" 的零值字段不设置；
+// 嵌套结构体的 env 标签作为其字段的前缀（如 `env:"DB_"`），未打标签的嵌套结构体直接展开。
+// 实现了 encoding.TextMarshaler 或 fmt.Stringer 的值（如 time.Duration）按其文本输出，
+// 切片以逗号连接，nil 指针字段跳过；v 不是结构体时不做任何修改
+func (t *Ts) SetEnvFromStruct(v any) *Ts {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return t
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return t
+	}
+	vars := make(map[string]string)
+	structEnv(rv, "", vars)
+	return t.SetEnv(vars)
+}
+
+// structEnv 递归收集结构体字段对应的环境变量
+func structEnv(rv reflect.Value, prefix string, vars map[string]string) {
+	rt := rv.Type()
+	for i := range rt.NumField() {
+		f := rt.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag, hasTag := f.Tag.Lookup("env")
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		fv := rv.Field(i)
+		for fv.Kind() == reflect.Pointer && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Pointer {
+			continue
+		}
+		if fv.Kind() == reflect.Struct && !isTextValue(fv) {
+			structEnv(fv, prefix+name, vars)
+			continue
+		}
+		if !hasTag || name == "" {
+			continue
+		}
+		if opts == "omitempty" && fv.IsZero() {
+			continue
+		}
+		if s, ok := envValue(fv); ok {
+			vars[prefix+name] = s
+		}
+	}
+}
+
+// isTextValue 判断值是否自带文本表示，这类结构体（如 time.Time）不再展开
+func isTextValue(v reflect.Value) bool {
+	if !v.CanInterface() {
+		return false
+	}
+	switch v.Interface().(type) {
+	case encoding.TextMarshaler, fmt.Stringer:
+		return true
+	}
+	return false
+}
+
+// envValue 将字段值格式化为环境变量值，不支持的类型返回 false
+func envValue(v reflect.Value) (string, bool) {
+	if v.CanInterface() {
+		switch x := v.Interface().(type) {
+		case encoding.TextMarshaler:
+			b, err := x.MarshalText()
+			return string(b), err == nil
+		case fmt.Stringer:
+			return x.String(), true
+		}
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), true
+	case reflect.Slice, reflect.Array:
+		parts := make([]string, 0, v.Len())
+		for i := range v.Len() {
+			s, ok := envValue(v.Index(i))
+			if !ok {
+				return "", false
+			}
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, ","), true
+	}
+	return "", false
+}
+
+// UnsetEnv 移除指定的环境变量
+func (t *Ts) UnsetEnv(keys ...string) *Ts {
+	t.Cfg.Env = slices.DeleteFunc(slices.Clone(t.baseEnv()), func(kv string) bool {
+		k, _, _ := strings.Cut(kv, "=")
+		return slices.Contains(keys, k)
+	})
+	return t
+}
+
+// PrependPath 将 dir 加到 PATH 最前面，使其中的程序优先被找到；PATH 不存在时新建。
+// 仅影响子进程的环境，argv 模式下 Args[0] 本身仍按当前进程的 PATH 查找
+func (t *Ts) PrependPath(dir string) *Ts {
+	return t.editPath(dir, true)
+}
+
+// AppendPath 将 dir 加到 PATH 末尾，PATH 不存在时新建
+func (t *Ts) AppendPath(dir string) *Ts {
+	return t.editPath(dir, false)
+}
+
+// editPath 修改最后一个 PATH 条目（与 os/exec 的取值一致），以系统路径分隔符连接
+func (t *Ts) editPath(dir string, prepend bool) *Ts {
+	env := slices.Clone(t.baseEnv())
+	for i := len(env) - 1; i >= 0; i-- {
+		k, v, ok := strings.Cut(env[i], "=")
+		if !ok || !isPathKey(k) {
+			continue
+		}
+		switch {
+		case v == "":
+			v = dir
+		case prepend:
+			v = dir + string(os.PathListSeparator) + v
+		default:
+			v = v + string(os.PathListSeparator) + dir
+		}
+		env[i] = k + "=" + v
+		t.Cfg.Env = env
+		return t
+	}
+	t.Cfg.Env = append(env, "PATH="+dir)
+	return t
+}
+
+// isPathKey 判断变量名是否为 PATH，Windows 上变量名不区分大小写（通常为 Path）
+func isPathKey(k string) bool {
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(k, "PATH")
+	}
+	return k == "PATH"
+}
+
+// LoadEnvFile 从 .env 文件读取 KEY=VALUE 并合并到环境变量，已存在的变量（包括通过 SetEnv
+// 设置的）保持不变。支持 # 注释、空行、export 前缀以及成对的单双引号
+func (t *Ts) LoadEnvFile(path string) (*Ts, error) {
+	return t.loadEnvFile(path, false)
+}
+
+// LoadEnvFileOverwrite 与 LoadEnvFile 相同，但文件中的值会覆盖已存在的变量
+func (t *Ts) LoadEnvFileOverwrite(path string) (*Ts, error) {
+	return t.loadEnvFile(path, true)
+}
+
+func (t *Ts) loadEnvFile(path string, overwrite bool) (*Ts, error) {
+	vars, err := parseEnvFile(path)
+	if err != nil {
+		return t, err
+	}
+	if !overwrite {
+		for k := range vars {
+			if _, ok := t.GetEnvVar(k); ok {
+				delete(vars, k)
+			}
+		}
+	}
+	return t.SetEnv(vars), nil
+}
+
+// parseEnvFile 解析 .env 文件，同名变量后出现的覆盖先出现的
+func parseEnvFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("mesh: read env file: %w", err)
+	}
+	vars := make(map[string]string)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		k, v, ok := strings.Cut(line, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" || strings.ContainsAny(k, " \t") {
+			return nil, fmt.Errorf("mesh: %s:%d: malformed line %q", path, n, sc.Text())
+		}
+		vars[k] = unquote(strings.TrimSpace(v))
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("mesh: read env file: %w", err)
+	}
+	return vars, nil
+}
+
+// unquote 去掉成对的首尾引号
+func unquote(v string) string {
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
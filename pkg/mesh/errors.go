@@ -0,0 +1,71 @@
+package mesh
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrTimeout 命令执行超过 Config.Timeout 被终止
+	ErrTimeout = errors.New("mesh: command timed out")
+	// ErrCanceled 父 context 被取消导致命令被终止
+	ErrCanceled = errors.New("mesh: command canceled")
+	// ErrInvalidConfig Config.Validate 发现配置错误
+	ErrInvalidConfig = errors.New("mesh: invalid config")
+	// ErrShellNotFound 配置的 Shell 及 FallbackShell 都不在 PATH 中
+	ErrShellNotFound = errors.New("mesh: shell not found")
+	// ErrInvalidDir 工作目录不存在或不是目录
+	ErrInvalidDir = errors.New("mesh: invalid working directory")
+	// ErrUserSwitch 无法以 Config.User 指定的用户运行，如用户不存在或当前进程权限不足
+	ErrUserSwitch = errors.New("mesh: cannot run as user")
+	// ErrChroot 无法在 Config.Chroot 指定的根目录下运行，如当前进程不是 root 或新根目录下缺少程序
+	ErrChroot = errors.New("mesh: cannot run in chroot")
+	// ErrCommandDenied 命令被 Config.CommandPolicy 或 DefaultCommandPolicy 拒绝，未启动进程
+	ErrCommandDenied = errors.New("mesh: command denied by policy")
+	// ErrIdleTimeout 命令连续 Config.IdleTimeout 没有任何输出被终止
+	ErrIdleTimeout = errors.New("mesh: command idle timed out")
+	// ErrOutputLimit 输出超过 MaxOutputBytes 且开启了 KillOnOutputLimit，进程被终止
+	ErrOutputLimit = errors.New("mesh: output limit exceeded")
+	// ErrExpectTimeout Expect 等待的提示在超时前未出现，进程被终止
+	ErrExpectTimeout = errors.New("mesh: expect timed out")
+	// ErrNotRunning 进程尚未启动或已经退出
+	ErrNotRunning = errors.New("mesh: process is not running")
+	// ErrCircuitOpen 命令连续失败次数过多，熔断冷却期内未执行
+	ErrCircuitOpen = errors.New("mesh: circuit breaker open")
+	// ErrStillRunning WaitTimeout 等待期间进程仍未结束
+	ErrStillRunning = errors.New("mesh: process is still running")
+	// ErrLimitExceeded 进程超过 RLimitCPU 被终止
+	ErrLimitExceeded = errors.New("mesh: resource limit exceeded")
+	// ErrEmptyOutput 需要解析 stdout 时输出为空
+	ErrEmptyOutput = errors.New("mesh: empty output")
+)
+
+// ExitError 描述一次失败的执行，由 AsError、Output 等返回
+type ExitError struct {
+	Cmd      string
+	ExitCode int    // 未能正常退出（超时、取消、未能启动）时为 -1
+	Stderr   string // 已按 KeepWhitespace 处理
+	Duration time.Duration
+	Err      error // 底层错误，如 *exec.ExitError 或包装了 ErrTimeout 的错误
+}
+
+// Error 返回包含命令、退出码与 stderr 的描述
+func (e *ExitError) Error() string {
+	if e.ExitCode == -1 && e.Err != nil {
+		return fmt.Sprintf("mesh: command %q: %v", e.Cmd, e.Err)
+	}
+	msg := fmt.Sprintf("mesh: command %q exited with code %d", e.Cmd, e.ExitCode)
+	if e.Stderr != "" {
+		msg += ": " + e.Stderr
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap 返回底层错误
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,27 @@
+package mesh
+
+// Executor 抽象一次命令执行，*Ts 实现该接口。
+// 上层代码依赖 Executor 而非 *Ts 时，测试中可注入 meshtest.FakeExecutor 等假实现
+type Executor interface {
+	Exec() *Ts
+}
+
+// Commander 按命令字符串创建 Executor
+type Commander interface {
+	Command(cmdStr string) Executor
+}
+
+// CommanderFunc 将函数适配为 Commander
+type CommanderFunc func(cmdStr string) Executor
+
+// Command 调用 f(cmdStr)
+func (f CommanderFunc) Command(cmdStr string) Executor {
+	return f(cmdStr)
+}
+
+// DefaultCommander 使用 New 创建真实派生进程的 Executor
+var DefaultCommander Commander = CommanderFunc(func(cmdStr string) Executor {
+	return New(cmdStr)
+})
+
+var _ Executor = (*Ts)(nil)
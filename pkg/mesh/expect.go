@@ -0,0 +1,123 @@
+package mesh
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// expectStep 是一步交互：等待 prompt 出现后写入 response
+type expectStep struct {
+	prompt   string
+	response string
+	timeout  time.Duration
+}
+
+// Expect 追加一步交互：stdout 或 stderr 中出现 prompt 后向 stdin 写入 response，多次调用按顺序依次匹配。
+// timeout 大于 0 时，若该步在上一步完成（第一步从进程启动）后 timeout 内仍未出现 prompt，
+// 则终止进程并返回 ErrExpectTimeout。
+// 设置 Expect 后 stdin 由其接管，SetStdin 不再生效，最后一步完成后关闭 stdin，且不能与 SudoPassword 同时使用；
+// response 不会自动追加换行；读取密码等直接访问终端的程序需配合 UsePTY
+func (t *Ts) Expect(prompt, response string, timeout time.Duration) *Ts {
+	t.expects = append(slices.Clip(t.expects), expectStep{prompt: prompt, response: response, timeout: timeout})
+	return t
+}
+
+// expecter 监视输出并按顺序回应提示，输入通过 os.Pipe 直接交给子进程，
+// 避免 os/exec 的 stdin 拷贝 goroutine 在进程退出后阻塞 Wait
+type expecter struct {
+	mu        sync.Mutex
+	steps     []expectStep
+	in        *os.File
+	out       *os.File
+	buf       []byte
+	timer     *time.Timer
+	gen       int
+	onTimeout func()
+	err       error
+}
+
+func newExpecter(steps []expectStep, onTimeout func()) (*expecter, error) {
+	in, out, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("mesh: expect: %w", err)
+	}
+	return &expecter{steps: slices.Clone(steps), in: in, out: out, onTimeout: onTimeout}, nil
+}
+
+// start 在进程启动后开始第一步的计时
+func (e *expecter) start() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.arm()
+}
+
+// Write 接收进程输出，匹配当前步骤的提示；跨多次写入的提示同样能匹配
+func (e *expecter) Write(p []byte) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.steps) == 0 || e.err != nil {
+		return len(p), nil
+	}
+	e.buf = append(e.buf, p...)
+	for len(e.steps) > 0 {
+		step := e.steps[0]
+		i := bytes.Index(e.buf, []byte(step.prompt))
+		if i < 0 {
+			// 只保留可能构成提示前缀的末尾部分
+			if keep := len(step.prompt) - 1; len(e.buf) > keep {
+				e.buf = append(e.buf[:0], e.buf[len(e.buf)-keep:]...)
+			}
+			break
+		}
+		e.buf = e.buf[i+len(step.prompt):]
+		e.steps = e.steps[1:]
+		_, _ = e.out.WriteString(step.response)
+		e.arm()
+	}
+	if len(e.steps) == 0 {
+		_ = e.out.Close()
+	}
+	return len(p), nil
+}
+
+// arm 为当前步骤重新计时，调用方需持有锁
+func (e *expecter) arm() {
+	e.gen++
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer = nil
+	}
+	if len(e.steps) == 0 || e.steps[0].timeout <= 0 {
+		return
+	}
+	gen, step := e.gen, e.steps[0]
+	e.timer = time.AfterFunc(step.timeout, func() {
+		e.mu.Lock()
+		expired := gen == e.gen && e.err == nil
+		if expired {
+			e.err = fmt.Errorf("%w: %q not seen within %s", ErrExpectTimeout, step.prompt, step.timeout)
+			_ = e.out.Close()
+		}
+		e.mu.Unlock()
+		if expired {
+			e.onTimeout()
+		}
+	})
+}
+
+// stop 停止计时并关闭管道，返回超时错误
+func (e *expecter) stop() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.gen++
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	_ = e.out.Close()
+	_ = e.in.Close()
+	return e.err
+}
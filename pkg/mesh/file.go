@@ -0,0 +1,51 @@
+package mesh
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WriteStdoutToFile 将未经 TrimSpace 的原始 stdout 原子地写入 path（先写临时文件再重命名），
+// 写入失败时不会留下不完整的目标文件
+func (t *Ts) WriteStdoutToFile(path string, perm os.FileMode) error {
+	return writeFileAtomic(path, t.stdoutRaw, perm)
+}
+
+// WriteStderrToFile 将未经 TrimSpace 的原始 stderr 原子地写入 path
+func (t *Ts) WriteStderrToFile(path string, perm os.FileMode) error {
+	return writeFileAtomic(path, t.stderrRaw, perm)
+}
+
+// writeFileAtomic 在目标目录中创建临时文件，写入并同步后重命名为 path
+func writeFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("mesh: write %s: %w", path, err)
+	}
+	tmp := f.Name()
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(tmp)
+			err = fmt.Errorf("mesh: write %s: %w", path, err)
+		}
+	}()
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	if err = f.Chmod(perm); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
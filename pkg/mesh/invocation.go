@@ -0,0 +1,46 @@
+package mesh
+
+import "strings"
+
+// Invocation 描述最近一次执行实际传给 exec.CommandContext 的调用方式，包括 sudo、资源限制等包装，
+// 便于审计与手动复现，例如确认是否回退到了 FallbackShell
+type Invocation struct {
+	Path   string   `json:"path"`   // 解析后的程序路径，查找失败时为原始名称
+	Args   []string `json:"args"`   // 完整的 argv，Args[0] 为程序名
+	Dir    string   `json:"dir"`    // 工作目录，设置了 Chroot 时为新根目录下的路径
+	Shell  string   `json:"shell"`  // 执行脚本的 Shell，argv 模式下为空
+	Script string   `json:"script"` // 经 stdin 传给 Shell 的脚本，脚本以参数传入或 argv 模式下为空
+	Stdin  bool     `json:"stdin"`  // 是否设置了调用方的 stdin（SetStdin 等）
+}
+
+// String 返回可粘贴到 POSIX Shell 中复现的命令行，经 stdin 传入的脚本以 here-document 附在后面
+func (inv Invocation) String() string {
+	quoted := make([]string, len(inv.Args))
+	for i, a := range inv.Args {
+		quoted[i] = shellQuote(a)
+	}
+	line := strings.Join(quoted, " ")
+	if inv.Dir != "" {
+		line = "cd " + shellQuote(inv.Dir) + " && " + line
+	}
+	if inv.Script != "" {
+		line += " <<'MESH_EOF'\n" + strings.TrimSuffix(inv.Script, "\n") + "\nMESH_EOF"
+	}
+	return line
+}
+
+// Invocation 返回最近一次执行的实际调用方式，尚未执行时为零值，命中缓存时为产生该结果的那次执行
+func (t *Ts) Invocation() Invocation {
+	return t.invocation
+}
+
+// shellQuote 按 POSIX Shell 规则为 s 加单引号，只含安全字符时原样返回
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_@%+=:,./-") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
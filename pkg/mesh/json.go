@@ -0,0 +1,158 @@
+package mesh
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Result 是执行结果的稳定 JSON 结构，适合投递到队列或持久化。
+// Duration 以纳秒整数序列化，Env 中的敏感变量已脱敏
+type Result struct {
+	Cmd        string        `json:"cmd"`
+	Args       []string      `json:"args,omitempty"`
+	Dir        string        `json:"dir,omitempty"`
+	Env        []string      `json:"env"`
+	Stdout     string        `json:"stdout"`
+	Stderr     string        `json:"stderr"`
+	ExitCode   int           `json:"exitCode"`
+	State      State         `json:"state"`
+	Error      string        `json:"error,omitempty"`
+	Duration   time.Duration `json:"duration"`
+	StartedAt  time.Time     `json:"startedAt"`
+	FinishedAt time.Time     `json:"finishedAt"`
+	Attempts   int           `json:"attempts"`
+	Pid        int           `json:"pid,omitempty"`
+	Truncated  bool          `json:"truncated,omitempty"`
+}
+
+// Result 返回当前执行结果的快照
+func (t *Ts) Result() Result {
+	return Result{
+		Cmd:        t.Cfg.Cmd,
+		Args:       t.Cfg.Args,
+		Dir:        t.Cfg.Dir,
+		Env:        t.maskedEnv(),
+		Stdout:     t.stdout,
+		Stderr:     t.stderr,
+		ExitCode:   t.exitCode,
+		State:      t.state,
+		Error:      errString(t.err),
+		Duration:   t.duration,
+		StartedAt:  t.startedAt,
+		FinishedAt: t.finishedAt,
+		Attempts:   t.attempts,
+		Pid:        t.pid,
+		Truncated:  t.truncated,
+	}
+}
+
+// FromResult 由已保存的结果构造 Ts，不会执行命令。
+// 用于回放录制的输出，使 Lines、Fields、ToMap、Table 等解析方法可直接作用于固定数据
+func FromResult(r Result) *Ts {
+	cfg := NewConfig()
+	cfg.Cmd = r.Cmd
+	cfg.Args = slices.Clone(r.Args)
+	cfg.Dir = r.Dir
+	cfg.Env = slices.Clone(r.Env)
+	t := &Ts{Cfg: cfg}
+	t.stdoutRaw = []byte(r.Stdout)
+	t.stderrRaw = []byte(r.Stderr)
+	t.stdout = t.output(r.Stdout)
+	t.stderr = t.output(r.Stderr)
+	t.exitCode = r.ExitCode
+	t.state = r.State
+	if t.state == StatePending {
+		t.state = StateExited
+	}
+	if r.Error != "" {
+		t.err = errors.New(r.Error)
+	}
+	t.duration = r.Duration
+	t.startedAt = r.StartedAt
+	t.finishedAt = r.FinishedAt
+	t.attempts = r.Attempts
+	t.pid = r.Pid
+	t.truncated = r.Truncated
+	return t
+}
+
+// MarshalJSON 以 Result 的结构序列化执行结果
+func (t *Ts) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Result())
+}
+
+// ToJSON 将 stdout 作为 JSON 解码到 v，命令失败或 stdout 为空时返回错误
+func (t *Ts) ToJSON(v any) error {
+	data, err := t.jsonOutput()
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("mesh: decode stdout as JSON: %w", err)
+	}
+	return nil
+}
+
+// ToJSONArray 将顶层为数组的 stdout 解码为逐个元素的原始 JSON，便于按需再解码
+func (t *Ts) ToJSONArray() ([]json.RawMessage, error) {
+	var items []json.RawMessage
+	if err := t.ToJSON(&items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (t *Ts) jsonOutput() ([]byte, error) {
+	if !t.Cfg.successCode(t.exitCode) {
+		return nil, fmt.Errorf("mesh: command exited with code %d: %s", t.exitCode, t.stderr)
+	}
+	if t.stdout == "" {
+		return nil, ErrEmptyOutput
+	}
+	return []byte(t.stdout), nil
+}
+
+// ToJSONLines 将 stdout 按 NDJSON（每行一个 JSON 值）逐行解码并回调 fn，空行会被跳过，
+// 适合 docker events 这类输出。解码失败或 fn 返回错误时立即停止，并返回包含行号（从 1 开始）的错误
+func (t *Ts) ToJSONLines(fn func(json.RawMessage) error) error {
+	if !t.Cfg.successCode(t.exitCode) {
+		return fmt.Errorf("mesh: command exited with code %d: %s", t.exitCode, t.stderr)
+	}
+	n := 0
+	for line := range t.LinesSeq() {
+		n++
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var msg json.RawMessage
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			return fmt.Errorf("mesh: decode json line %d: %w", n, err)
+		}
+		if err := fn(msg); err != nil {
+			return fmt.Errorf("mesh: json line %d: %w", n, err)
+		}
+	}
+	return nil
+}
+
+// ScanJSONLines 将 stdout 的每个非空行解码为 T，错误规则与 ToJSONLines 相同
+func ScanJSONLines[T any](t *Ts) ([]T, error) {
+	var items []T
+	err := t.ToJSONLines(func(msg json.RawMessage) error {
+		var v T
+		if err := json.Unmarshal(msg, &v); err != nil {
+			return err
+		}
+		items = append(items, v)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
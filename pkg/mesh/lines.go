@@ -0,0 +1,47 @@
+package mesh
+
+import "slices"
+
+// LineSet 是可链式变换的行集合，例如：
+// t.LineSet().Filter(func(l string) bool { return l != "" }).Map(strings.ToUpper).Collect()
+type LineSet []string
+
+// LineSet 以 Lines 的结果构造 LineSet
+func (t *Ts) LineSet() LineSet {
+	return LineSet(t.Lines())
+}
+
+// Map 对 stdout 的每一行应用 fn
+func (t *Ts) Map(fn func(string) string) []string {
+	return t.LineSet().Map(fn)
+}
+
+// Filter 返回 stdout 中 fn 返回 true 的行
+func (t *Ts) Filter(fn func(string) bool) []string {
+	return t.LineSet().Filter(fn)
+}
+
+// Map 返回对每一行应用 fn 后的新集合
+func (s LineSet) Map(fn func(string) string) LineSet {
+	out := make(LineSet, 0, len(s))
+	for _, line := range s {
+		out = append(out, fn(line))
+	}
+	return out
+}
+
+// Filter 返回 fn 返回 true 的行组成的新集合
+func (s LineSet) Filter(fn func(string) bool) LineSet {
+	out := make(LineSet, 0, len(s))
+	for _, line := range s {
+		if fn(line) {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// Collect 返回集合中行的副本
+func (s LineSet) Collect() []string {
+	return slices.Clone([]string(s))
+}
@@ -0,0 +1,74 @@
+package mesh
+
+import "log/slog"
+
+// SetLogger 设置执行日志，命令启动时输出 Debug 记录，结束时成功输出 Info、非零退出输出 Warn、
+// 超时或无法启动等错误输出 Error。环境变量按 SecretKeys 脱敏，nil 表示不输出日志；
+// 日志记录携带 Ts.Context，Handler 可从中提取请求 ID 等信息
+func (t *Ts) SetLogger(l *slog.Logger) *Ts {
+	t.Cfg.Logger = l
+	return t
+}
+
+func (t *Ts) logStart() {
+	l := t.Cfg.Logger
+	if l == nil {
+		return
+	}
+	l.LogAttrs(t.Context(), slog.LevelDebug, "mesh: command started",
+		slog.String("cmd", t.Cfg.Cmd),
+		slog.String("dir", t.Cfg.Dir),
+		slog.Int("pid", t.pid),
+		slog.Bool("dryRun", t.dryRun),
+		slog.Any("env", t.maskedEnv()),
+	)
+}
+
+// logFallback 记录 Shell 回退，避免 bash 语法在 sh 下执行失败时难以排查
+func (t *Ts) logFallback(shell string) {
+	l := t.Cfg.Logger
+	if l == nil {
+		return
+	}
+	l.LogAttrs(t.Context(), slog.LevelWarn, "mesh: shell not found, using fallback",
+		slog.String("shell", t.Cfg.Shell),
+		slog.String("fallback", shell),
+	)
+}
+
+// logUnknownShell 记录未适配的 Shell，其调用方式按 POSIX 处理，可能不符合预期
+func (t *Ts) logUnknownShell(shell string) {
+	l := t.Cfg.Logger
+	if l == nil {
+		return
+	}
+	l.LogAttrs(t.Context(), slog.LevelWarn, "mesh: unknown shell, invoking as POSIX sh",
+		slog.String("shell", shell),
+	)
+}
+
+func (t *Ts) logFinish() {
+	l := t.Cfg.Logger
+	if l == nil {
+		return
+	}
+	level := slog.LevelInfo
+	switch {
+	case t.state != StateExited:
+		level = slog.LevelError
+	case !t.IsSuccess():
+		level = slog.LevelWarn
+	}
+	attrs := []slog.Attr{
+		slog.String("cmd", t.Cfg.Cmd),
+		slog.Int("exitCode", t.exitCode),
+		slog.String("state", t.state.String()),
+		slog.Duration("duration", t.duration),
+		slog.Bool("timeout", t.IsTimeout()),
+		slog.Int("attempt", t.attempts),
+	}
+	if t.err != nil {
+		attrs = append(attrs, slog.String("error", t.err.Error()))
+	}
+	l.LogAttrs(t.Context(), level, "mesh: command finished", attrs...)
+}
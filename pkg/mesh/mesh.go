@@ -1,38 +1,241 @@
 package mesh
 
 import (
-	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"iter"
+	"log/slog"
+	"maps"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/time/rate"
 )
 
-// Config 定义了可选参数的配置结构体
+// Config 定义了可选参数的配置结构体。
+// Timeout 小于等于 0 表示不限制执行时间，推荐使用 NewConfig 获取带默认值的实例
 type Config struct {
 	Cmd     string        `note:"cmd" default:"-"`
 	Shell   string        `note:"shell" default:"bash"`
 	Timeout time.Duration `note:"timeout" default:"60s"`
 	Env     []string      `note:"envVars" default:"system"`
+	Combine bool          `note:"combineOutput" default:"false"`
+	Args    []string      `note:"argv" default:"-"`
+	Dir     string        `note:"dir" default:"-"`
+	// RedirectStderrToStdout 为 true 时相当于 2>&1，stderr 按输出顺序写入 Stdout，Stderr 为空；
+	// 与 Combine 同时开启时以 Combine 为准
+	RedirectStderrToStdout bool `note:"redirectStderrToStdout" default:"false"`
+	// ShellArgs 插在 Shell 与脚本之间的参数，如 -e、-o pipefail、-l；POSIX Shell 默认从 stdin 读取脚本，
+	// ShellArgs 以 -c 结尾时改为以参数传入。argv 模式下不生效
+	ShellArgs []string `note:"shellArgs" default:"-"`
+	// FallbackShell 是 Shell 不在 PATH 中时改用的 Shell，为空时使用平台默认值（Windows 为 cmd，其余为 sh），
+	// 设为 "-" 表示不回退、直接返回 ErrShellNotFound；实际使用的 Shell 可通过 ShellUsed 查看
+	FallbackShell string `note:"fallbackShell" default:"sh"`
+	// Sudo 为 true 时通过 sudo 提权执行；SudoPassword 为空时使用 sudo -n，需要密码则直接失败，
+	// 否则以 sudo -S 从 stdin 读取密码。密码不会出现在 Show 与日志中
+	Sudo         bool   `note:"sudo" default:"false"`
+	SudoPassword string `note:"sudoPassword" default:"-"`
+	// User 非空时以该用户（用户名或 uid）运行命令，仅支持 Unix，通常要求当前进程为 root；
+	// 环境变量不会随之改变，HOME 等需要自行设置
+	User string `note:"user" default:"-"`
+	// Chroot 非空时子进程以该目录为根目录运行（仅 Unix），要求当前进程为 root，与 User 配合可构成简单的沙箱。
+	// 此时 Dir 按新的根目录解释（为空时为 /），Shell 或 Args[0] 仍按当前进程的 PATH 解析，须在新根目录下的相同路径存在
+	Chroot string `note:"chroot" default:"-"`
+	// NewPIDNamespace 等为 true 时子进程在新建的 PID、网络、挂载、UTS、IPC 命名空间中运行（仅 Linux，通常要求 root），
+	// 无需容器运行时即可获得轻量隔离。新 PID 命名空间中进程的 pid 为 1，但 /proc 仍是宿主的，需配合 Chroot 自行挂载；
+	// 新网络命名空间只有未启用的 lo；在其余平台上设置这些选项会导致执行失败
+	NewPIDNamespace   bool `note:"newPIDNamespace" default:"false"`
+	NewNetNamespace   bool `note:"newNetNamespace" default:"false"`
+	NewMountNamespace bool `note:"newMountNamespace" default:"false"`
+	NewUTSNamespace   bool `note:"newUTSNamespace" default:"false"`
+	NewIPCNamespace   bool `note:"newIPCNamespace" default:"false"`
+	// ValidateOnExec 为 true 时每次执行前先调用 Validate，配置有误则不启动进程
+	ValidateOnExec bool `note:"validateOnExec" default:"false"`
+	// DryRun 为 true 时只解析调用方式而不启动进程，exitCode 为 0，解析结果见 Show 的 plan；
+	// 钩子与日志照常触发，便于审计执行计划
+	DryRun bool `note:"dryRun" default:"false"`
+	// CacheTTL 大于 0 时，相同命令、环境与执行、输出相关配置的成功结果在 TTL 内直接复用，
+	// 不再重新执行；设置了 stdin、Expect、ExtraFiles、逐行回调、输出 Writer 或 StdoutChan 的命令以及 DryRun
+	// 既不读取也不写入缓存
+	CacheTTL time.Duration `note:"cacheTTL" default:"0s"`
+	// BreakerThreshold 大于 0 时开启熔断：同一命令连续失败达到该次数后，BreakerCooldown 内的 Exec
+	// 直接返回 ErrCircuitOpen 而不启动进程；冷却结束后放行一次试探，成功即恢复
+	BreakerThreshold int           `note:"breakerThreshold" default:"0"`
+	BreakerCooldown  time.Duration `note:"breakerCooldown" default:"30s"`
+	// IdleTimeout 大于 0 时，stdout、stderr 连续该时长没有任何输出即终止进程，适合下载这类耗时长、但应持续输出进度的命令。
+	// 从进程启动开始计时，每次输出重置，stdout、stderr 都关闭（通常即进程退出）后停止；与 Timeout 同时生效，被终止时 IsTimeout 为 true，Err 包含 ErrIdleTimeout
+	IdleTimeout time.Duration `note:"idleTimeout" default:"0s"`
+	// KillGracePeriod 超时或取消时先发送 SIGTERM，等待该时长后仍未退出再 SIGKILL，0 表示立即 SIGKILL
+	KillGracePeriod time.Duration `note:"killGracePeriod" default:"0s"`
+	// CPUAffinity 非空时将进程绑定到这些 CPU 编号上运行，其派生的子进程同样继承，仅 Linux 生效，其余平台忽略
+	CPUAffinity []int `note:"cpuAffinity" default:"-"`
+	// RLimitCPU、RLimitAS 分别限制进程可用的 CPU 时间与虚拟内存字节数（仅 Unix，0 表示不限制），
+	// 由 /bin/sh 的 ulimit 设置后再 exec 原命令。超过 CPU 时间被终止时 LimitExceeded 为 true、
+	// Err 包含 ErrLimitExceeded；超过地址空间限制表现为程序内存分配失败，无法可靠识别
+	RLimitCPU time.Duration `note:"rlimitCPU" default:"0s"`
+	RLimitAS  uint64        `note:"rlimitAS" default:"0"`
+	// Nice 非 0 时在进程启动后立即将其 nice 值设为该值（-20 到 19，仅 Unix），正值降低优先级，
+	// 负值通常需要 root；设置失败时进程会被终止并返回错误
+	Nice int `note:"nice" default:"0"`
+	// MaxOutputBytes 限制 stdout、stderr 各自保留的字节数，0 表示不限制；超出后按 TruncateMode
+	// 保留开头或末尾（丢弃的字节数见 StdoutDropped、StderrDropped），KillOnOutputLimit 为 true 时同时终止进程
+	MaxOutputBytes    int          `note:"maxOutputBytes" default:"0"`
+	TruncateMode      TruncateMode `note:"truncateMode" default:"head"`
+	KillOnOutputLimit bool         `note:"killOnOutputLimit" default:"false"`
+
+	// Stdout、Stderr 默认返回去掉首尾空白的内容，KeepWhitespace 为 true 时保留首尾空白；
+	// 原始内容始终可通过 StdoutRaw 获取。取反命名使手动构造的 Config 零值保持去空白行为
+	KeepWhitespace bool `note:"keepWhitespace" default:"false"`
+	// StripANSI 为 true 时 Stdout、Stderr 去除 ANSI 转义序列（颜色、光标控制等），StdoutRaw 保留原样
+	StripANSI bool `note:"stripANSI" default:"false"`
+	// Encoding 非空时将捕获的输出从该字符集（如 gbk、gb18030、big5）解码为 UTF-8，
+	// StdoutRaw、StdoutBytes 与逐行回调同样得到解码后的内容；为空时按 UTF-8 原样保留
+	Encoding string `note:"encoding" default:"-"`
+	// UsePTY 为 true 时为进程分配伪终端（仅 Unix），适用于检查 isatty 的程序。
+	// 此时 stderr 与 stdout 合并到 Stdout，写入的 stdin 会被终端回显，Shell 脚本改用 -c 传入；终端会把 \n 转换为 \r\n，
+	// Stdout 中的 \r\n 会还原为 \n，StdoutRaw 保留终端的原始输出
+	UsePTY bool `note:"usePTY" default:"false"`
+	// ExtraFiles 额外传给子进程的文件，与 os/exec 一致，子进程中 ExtraFiles[i] 的描述符为 3+i，
+	// 可用于传递管道、socket 等（Windows 不支持）。文件由调用方负责关闭，如管道的写端应在 Start 后关闭，
+	// 否则读端读不到 EOF；设置了 ExtraFiles 的命令不会被缓存
+	ExtraFiles []*os.File `note:"extraFiles" default:"-"`
+	// Env 为 nil 时默认继承当前进程的环境变量，NoInheritEnv 为 true 时不再继承，仅使用 Env。
+	// 取反命名使手动构造的 Config 零值保持继承行为
+	NoInheritEnv bool `note:"noInheritEnv" default:"false"`
+	// SecretKeys 中的变量以及名称包含 SecretPatterns 的变量在 Show 中显示为 ***
+	SecretKeys []string `note:"secretKeys" default:"-"`
+
+	// RateLimiter 非 nil 时每次启动进程前等待令牌，为 nil 时使用 DefaultRateLimiter；
+	// 等待受 context 与 Timeout 约束，DryRun 不消耗令牌
+	RateLimiter *rate.Limiter `note:"rateLimiter" default:"-"`
+	// CommandPolicy 非 nil 时每次启动进程前以要执行的命令调用，返回错误则拒绝执行；为 nil 时使用 DefaultCommandPolicy。
+	// argv 模式下传入的是以空格连接的 Args，见 AllowPrefixes
+	CommandPolicy func(cmd string) error `note:"commandPolicy" default:"-"`
+
+	Retries          int                                `note:"retries" default:"0"`
+	RetryDelay       time.Duration                      `note:"retryDelay" default:"0s"`
+	RetryExponential bool                               `note:"retryExponential" default:"false"`
+	RetryExitCodes   []int                              `note:"retryExitCodes" default:"-"`
+	RetryIf          func(code int, stderr string) bool `note:"retryIf" default:"-"`
+
+	// SuccessExitCodes 视为成功的退出码，为空时仅 0。grep、diff 等以退出码 1 表示"无匹配""有差异"，
+	// 可设为 {0, 1}；IsSuccess、AsError、Output、MustExec、重试、熔断与缓存均按此判断，ExitCode 仍返回实际值
+	SuccessExitCodes []int `note:"successExitCodes" default:"0"`
+
+	Logger *slog.Logger `note:"logger" default:"-"`
+	// Metrics 非 nil 时每次进程运行结束后记录执行指标，见 MemoryMetrics
+	Metrics Metrics `note:"metrics" default:"-"`
+	// BeforeExec 在每次启动进程前调用，AfterExec 在 Wait 填充结果后调用，
+	// 失败、超时或未能启动时 AfterExec 同样会被调用，可用于埋点、追踪与审计；调用方的 context 可通过 Ts.Context 获取
+	BeforeExec func(*Ts) `note:"beforeExec" default:"-"`
+	AfterExec  func(*Ts) `note:"afterExec" default:"-"`
 }
 
 // NewConfig 返回一个包含默认值的 Config 实例
 func NewConfig() *Config {
 	return &Config{
 		Cmd:     "",               // 默认命令
-		Shell:   "bash",           // 默认 Shell
+		Shell:   defaultShell(),   // 默认 Shell
 		Timeout: 60 * time.Second, // 默认超时时间
 		Env:     os.Environ(),     // 默认环境变量
+
+		FallbackShell:   fallbackShell(),
+		BreakerCooldown: 30 * time.Second,
 	}
 }
 
+// clone 深拷贝 Config，切片字段不再与原配置共享底层数组
+func (c *Config) clone() *Config {
+	cp := *c
+	cp.Env = slices.Clone(c.Env)
+	cp.Args = slices.Clone(c.Args)
+	cp.ShellArgs = slices.Clone(c.ShellArgs)
+	cp.CPUAffinity = slices.Clone(c.CPUAffinity)
+	cp.RetryExitCodes = slices.Clone(c.RetryExitCodes)
+	cp.SuccessExitCodes = slices.Clone(c.SuccessExitCodes)
+	cp.SecretKeys = slices.Clone(c.SecretKeys)
+	cp.ExtraFiles = slices.Clone(c.ExtraFiles)
+	return &cp
+}
+
 type Ts struct {
-	Cfg      *Config
-	stdout   string
-	stderr   string
-	exitCode int
+	Cfg        *Config
+	ctx        context.Context
+	stdout     string
+	stderr     string
+	stdoutRaw  []byte
+	stderrRaw  []byte
+	exitCode   int
+	duration   time.Duration
+	startedAt  time.Time
+	finishedAt time.Time
+	pid        int
+	truncated  bool
+	dropped    [2]int64
+	dryRun     bool
+	cached     bool
+	combined   string
+	err        error
+	state      State
+	attempts   int
+	shellUsed  string
+	limitHit   bool
+	reaped     bool
+	invocation Invocation
+	run        *running
+	cancelReq  bool
+	cancelCh   chan struct{}
+
+	onStdoutLine func(string)
+	stdoutCh     *lineChan
+	onStderrLine func(string)
+	stdin        func() io.Reader
+	stdoutW      io.Writer
+	stderrW      io.Writer
+	expects      []expectStep
+	// checked 为 true 表示 Exec 已在分派前完成检查，prepare 不再重复
+	checked bool
+}
+
+// runMu 保护 Ts 的 run 交接与取消请求，使 Cancel 可以在其他 goroutine 中调用
+var runMu sync.Mutex
+
+// running 保存一次执行过程中的进程与输出缓冲
+type running struct {
+	cmd      *exec.Cmd
+	parent   context.Context
+	ctx      context.Context
+	cancel   context.CancelFunc
+	startErr error
+	waited   bool
+	waitOnce sync.Once
+	done     chan struct{}
+	stdout   *limitBuffer
+	stderr   *limitBuffer
+	combined *syncBuffer
+
+	limitKilled atomic.Bool
+	idleKilled  atomic.Bool
+	idle        *idleTimer
+	canceled    atomic.Bool
+
+	stdoutLines *lineWriter
+	stdoutCh    *lineChan
+	stderrLines *lineWriter
+	enc         encoding.Encoding
+	pty         *ptyConn
+	expect      *expecter
 }
 
 func New(cmdStr string, config ...*Config) *Ts {
@@ -50,15 +253,234 @@ func New(cmdStr string, config ...*Config) *Ts {
 	}
 }
 
-// SetEnv 追加或覆盖某些环境变量
+// NewExec 以参数列表方式直接执行程序，不经过 Shell 解析，
+// 适合参数来自不可信输入的场景；此模式下 Shell 配置被忽略
+func NewExec(name string, args ...string) *Ts {
+	cfg := NewConfig()
+	cfg.Args = append([]string{name}, args...)
+	cfg.Cmd = strings.Join(cfg.Args, " ")
+	return &Ts{
+		Cfg: cfg,
+	}
+}
+
+// NewScript 执行脚本文件：首行为 #! 时使用其指定的解释器，否则以 Cfg.Shell（及 ShellArgs）执行，
+// 文件以路径参数传入而不是读入字符串，报错中的文件名与行号保持不变。
+// 以 argv 模式运行，Cmd 仅用于展示；文件无法读取时由解释器报告错误
+func NewScript(path string, config ...*Config) *Ts {
+	var cfg *Config
+	if len(config) > 0 && config[0] != nil {
+		cfg = config[0]
+	} else {
+		cfg = NewConfig()
+	}
+	if interp := readShebang(path); interp != nil {
+		cfg.Args = append(interp, path)
+	} else {
+		cfg.Args = scriptArgs(cfg.Shell, cfg.ShellArgs, path)
+	}
+	cfg.Cmd = strings.Join(cfg.Args, " ")
+	return &Ts{
+		Cfg: cfg,
+	}
+}
+
+// Clone 返回一个独立的副本：Config 被深拷贝，执行结果被清空，
+// 适合将配置好的 Ts 作为模板反复派生新的执行
+func (t *Ts) Clone() *Ts {
+	c := *t
+	c.Cfg = t.Cfg.clone()
+	c.stdoutCh = nil // 通道只属于原 Ts 的下一次执行
+	c.clearResult()
+	return &c
+}
+
+// Reset 清空 stdout、stderr、exitCode 等执行结果，使同一个 Ts 可以再次执行。
+// Exec 在上次结果为非零退出码时会直接返回已有结果，需要先 Reset 才会重新执行；
+// 若命令仍在运行，Reset 会先终止并回收该进程
+func (t *Ts) Reset() *Ts {
+	if t.run != nil && !t.run.waited {
+		t.run.cancel()
+		t.Wait()
+	}
+	t.clearResult()
+	return t
+}
+
+// clearResult 清空执行结果与运行状态，保留配置
+func (t *Ts) clearResult() {
+	t.stdout = ""
+	t.stderr = ""
+	t.stdoutRaw = nil
+	t.stderrRaw = nil
+	t.exitCode = 0
+	t.duration = 0
+	t.startedAt = time.Time{}
+	t.finishedAt = time.Time{}
+	t.pid = 0
+	t.truncated = false
+	t.dropped = [2]int64{}
+	t.dryRun = false
+	t.cached = false
+	t.combined = ""
+	t.err = nil
+	t.state = StatePending
+	t.attempts = 0
+	t.shellUsed = ""
+	t.limitHit = false
+	t.reaped = false
+	t.invocation = Invocation{}
+	runMu.Lock()
+	t.run = nil
+	t.cancelReq = false
+	t.cancelCh = nil
+	runMu.Unlock()
+}
+
+// setRun 切换当前执行，已有取消请求时新的执行会立即被取消
+func (t *Ts) setRun(r *running) {
+	runMu.Lock()
+	defer runMu.Unlock()
+	t.run = r
+	if r != nil && t.cancelReq {
+		r.canceled.Store(true)
+		r.cancel()
+	}
+}
+
+// canceledCh 返回调用 Cancel 时关闭的通道
+func (t *Ts) canceledCh() <-chan struct{} {
+	runMu.Lock()
+	defer runMu.Unlock()
+	if t.cancelCh == nil {
+		t.cancelCh = make(chan struct{})
+		if t.cancelReq {
+			close(t.cancelCh)
+		}
+	}
+	return t.cancelCh
+}
+
+// WithShell 设置执行脚本的 Shell（名称或路径）。bash、sh、zsh、fish、powershell、pwsh、cmd
+// 按各自的方式传入脚本，其余 Shell 按 POSIX 方式调用，执行时会通过 Logger 输出一条 Warn
+func (t *Ts) WithShell(name string) *Ts {
+	t.Cfg.Shell = name
+	return t
+}
+
+// SetCmd 替换要执行的 Shell 命令并清空上次的结果（内部调用 Reset），
+// 因此上次非零退出时 Exec 的提前返回不会影响新命令；Args 会被清空以回到 Shell 模式。
+// Config 可能与其他 Ts 共享，需要保留原命令时先 Clone：t.Clone().SetCmd("...").Exec()
+func (t *Ts) SetCmd(cmdStr string) *Ts {
+	t.Reset()
+	t.Cfg.Cmd = cmdStr
+	t.Cfg.Args = nil
+	return t
+}
+
+// WithContext 设置父 context，Exec 会基于它派生超时 context，
+// 调用方可以通过取消父 context 来中断正在执行的命令
+func (t *Ts) WithContext(ctx context.Context) *Ts {
+	t.ctx = ctx
+	return t
+}
+
+// ExecContext 以 ctx 作为父 context 执行命令，ctx 同时通过 Context 提供给钩子、日志与指标，
+// 便于关联请求 ID、追踪 span 等调用方信息
+func (t *Ts) ExecContext(ctx context.Context) *Ts {
+	return t.WithContext(ctx).Exec()
+}
+
+// Context 返回通过 WithContext 或 ExecContext 设置的 context，未设置时返回 context.Background()
+func (t *Ts) Context() context.Context {
+	if t.ctx == nil {
+		return context.Background()
+	}
+	return t.ctx
+}
+
+// WithTimeout 返回使用指定超时时间的副本，原 Ts 的配置不受影响，
+// 适合以同一个模板执行耗时差异较大的命令：t.WithTimeout(5*time.Second).Exec()
+func (t *Ts) WithTimeout(d time.Duration) *Ts {
+	c := t.Clone()
+	c.Cfg.Timeout = d
+	return c
+}
+
+// SetDir 设置命令的工作目录，为空时继承当前进程的工作目录
+func (t *Ts) SetDir(path string) *Ts {
+	t.Cfg.Dir = path
+	return t
+}
+
+// SetCombined 开启或关闭合并输出，开启后 stdout 与 stderr 按实际输出顺序
+// 交织写入同一缓冲区，通过 Combined 读取
+func (t *Ts) SetCombined(on bool) *Ts {
+	t.Cfg.Combine = on
+	return t
+}
+
+// OnStdoutLine 设置 stdout 的逐行回调，命令执行期间每输出一行即回调一次，
+// 完整输出仍会保存在 Stdout 中；回调中的 panic 会被忽略
+func (t *Ts) OnStdoutLine(fn func(string)) *Ts {
+	t.onStdoutLine = fn
+	return t
+}
+
+// StdoutChan 返回在下一次执行期间逐行接收 stdout 的通道，进程结束后通道被关闭，适合配合 select 使用；
+// 完整输出仍会保存在 Stdout 中。通道的缓冲有限，读取跟不上时进程会因输出管道写满而阻塞，
+// 因此应在其他 goroutine 中读取，或先 Start 再 range 通道（此时由后台完成回收，结束后再调用 Wait 获取结果）。
+// 仅对调用后的第一次运行生效，重试不再发送；命中缓存或被熔断时不会执行，通道直接关闭
+func (t *Ts) StdoutChan() <-chan string {
+	if t.stdoutCh == nil {
+		t.stdoutCh = newLineChan()
+	}
+	return t.stdoutCh.ch
+}
+
+// OnStderrLine 设置 stderr 的逐行回调，行为与 OnStdoutLine 一致
+func (t *Ts) OnStderrLine(fn func(string)) *Ts {
+	t.onStderrLine = fn
+	return t
+}
+
+// SetStdoutWriter 在捕获 stdout 的同时实时写入 w（如 os.Stdout 或日志文件），nil 表示不转发
+func (t *Ts) SetStdoutWriter(w io.Writer) *Ts {
+	t.stdoutW = w
+	return t
+}
+
+// SetStderrWriter 在捕获 stderr 的同时实时写入 w，nil 表示不转发
+func (t *Ts) SetStderrWriter(w io.Writer) *Ts {
+	t.stderrW = w
+	return t
+}
+
+// SetStdin 设置子进程的标准输入，reader 只会被读取一次，重试时不会重新提供。
+// 仅在 argv 模式及以参数传入脚本的 Shell（cmd、PowerShell，或设置了 SudoPassword）下生效，
+// bash、sh 等 Shell 模式下 stdin 用于传入脚本本身，设置的输入会被忽略
+func (t *Ts) SetStdin(r io.Reader) *Ts {
+	t.stdin = func() io.Reader { return r }
+	return t
+}
+
+// SetStdinString 以字符串作为子进程的标准输入，每次执行都会重新提供完整内容，
+// 生效范围与 SetStdin 相同
+func (t *Ts) SetStdinString(s string) *Ts {
+	t.stdin = func() io.Reader { return strings.NewReader(s) }
+	return t
+}
+
+// SetEnv 追加或覆盖某些环境变量，需要只保留给定变量时使用 SetEnvReplace
 func (t *Ts) SetEnv(envVars map[string]string) *Ts {
-	newEnv := make([]string, 0, len(t.Cfg.Env)+len(envVars))
+	base := t.baseEnv()
+	newEnv := make([]string, 0, len(base)+len(envVars))
 	existingKeys := make(map[string]bool, len(envVars))
 	for k := range envVars {
 		existingKeys[k] = false
 	}
 
-	for _, kv := range t.Cfg.Env {
+	for _, kv := range base {
 		parts := strings.SplitN(kv, "=", 2)
 		if len(parts) != 2 {
 			continue
@@ -77,14 +499,52 @@ func (t *Ts) SetEnv(envVars map[string]string) *Ts {
 	return t
 }
 
+// SetEnvReplace 丢弃现有环境变量，仅使用 envVars，且不再继承当前进程的环境，
+// 相当于 ClearEnv 后再 SetEnv；变量按名称排序，便于比较与缓存
+func (t *Ts) SetEnvReplace(envVars map[string]string) *Ts {
+	env := make([]string, 0, len(envVars))
+	for _, k := range slices.Sorted(maps.Keys(envVars)) {
+		env = append(env, k+"="+envVars[k])
+	}
+	t.Cfg.Env = env
+	t.Cfg.NoInheritEnv = true
+	return t
+}
+
 func (t *Ts) GetEnv() []string {
-	envCopy := make([]string, len(t.Cfg.Env))
-	copy(envCopy, t.Cfg.Env)
+	base := t.baseEnv()
+	envCopy := make([]string, len(base))
+	copy(envCopy, base)
 	return envCopy
 }
 
 func (t *Ts) Lines() []string {
-	trimSpace := t.Stdout()
+	trimSpace := strings.TrimSpace(t.Stdout())
+	if trimSpace == "" {
+		return []string{}
+	}
+	return strings.Split(trimSpace, "\n")
+}
+
+// LinesSeq 逐行迭代 stdout，结果与 Lines 一致，但按需切分而不构造整个切片，适合处理大量输出：
+// for line := range t.LinesSeq() { ... }
+func (t *Ts) LinesSeq() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		trimSpace := strings.TrimSpace(t.Stdout())
+		if trimSpace == "" {
+			return
+		}
+		for line := range strings.SplitSeq(trimSpace, "\n") {
+			if !yield(line) {
+				return
+			}
+		}
+	}
+}
+
+// StderrLines 按行切分 stderr，与 Lines 对称
+func (t *Ts) StderrLines() []string {
+	trimSpace := strings.TrimSpace(t.Stderr())
 	if trimSpace == "" {
 		return []string{}
 	}
@@ -119,48 +579,616 @@ func (t *Ts) ToMap(expectedLen int) map[string][]string {
 	return data
 }
 
+// Exec 同步执行命令，等价于 Start 后立即 Wait。
+// 上次执行以非零（且不属于 SuccessExitCodes）的退出码结束时直接返回已有结果，调用 Reset 后才会重新执行。
+// 配置了 Retries 时，满足重试条件的非零退出会间隔 RetryDelay 重试（RetryExponential 时间隔逐次翻倍），
+// 结果以最后一次执行为准；父 context 取消会立即结束重试。
+// 重试条件：RetryIf 非空时由其决定，否则 RetryExitCodes 非空时仅重试其中的退出码，
+// 两者都未设置时任何非零退出都会重试
 func (t *Ts) Exec() *Ts {
-	if t.exitCode != 0 && t.exitCode != -1 {
+	if t.exitCode != 0 && t.exitCode != -1 && !t.Cfg.successCode(t.exitCode) {
 		return t
 	}
+	if t.run != nil && t.run.waited {
+		t.setRun(nil)
+	}
+	run := t.execRetry
+	if t.cacheable() {
+		run = t.execCached
+	}
+	if t.Cfg.BreakerThreshold > 0 {
+		inner := run
+		run = func() *Ts { return t.execBreaker(inner) }
+	}
+	// 在分派前检查，缓存命中、熔断与重试都不能绕过
+	if err := t.checkExec(); err != nil {
+		t.clearResult()
+		t.err = err
+		t.exitCode = -1
+		t.state = StateNotStarted
+	} else {
+		t.checked = true
+		run()
+		t.checked = false
+	}
+	// 熔断或检查未通过时不会启动进程，未被消费的通道需要关闭
+	if ch := t.stdoutCh; ch != nil {
+		t.stdoutCh = nil
+		ch.close()
+	}
+	return t
+}
 
-	shell := t.Cfg.Shell
-	if _, err := exec.LookPath(shell); err != nil {
-		shell = "sh"
+// execRetry 执行命令并按配置重试
+func (t *Ts) execRetry() *Ts {
+	t.attempts = 0
+	delay := t.Cfg.RetryDelay
+	for {
+		t.Start().Wait()
+		if t.attempts > t.Cfg.Retries || !t.retryable() {
+			return t
+		}
+		if !t.sleep(delay) {
+			return t
+		}
+		if t.Cfg.RetryExponential {
+			delay *= 2
+		}
+		t.setRun(nil)
 	}
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), t.Cfg.Timeout)
-	defer cancel()
+// retryable 判断本次结果是否需要重试，未配置条件时任何非零退出都会重试
+func (t *Ts) retryable() bool {
+	if t.exitCode == 0 || t.IsSuccess() || errors.Is(t.err, ErrCanceled) || errors.Is(t.err, ErrCommandDenied) {
+		return false
+	}
+	if t.Cfg.RetryIf != nil {
+		return t.Cfg.RetryIf(t.exitCode, t.stderr)
+	}
+	if len(t.Cfg.RetryExitCodes) > 0 {
+		return slices.Contains(t.Cfg.RetryExitCodes, t.exitCode)
+	}
+	return true
+}
 
-	cmd := exec.CommandContext(ctx, shell)
-	cmd.Env = t.Cfg.Env
-	cmd.Stdin = strings.NewReader(t.Cfg.Cmd)
+// MustExec 执行命令，结果不成功（见 IsSuccess）时 panic，仅适合脚本与测试等一次性场景
+func (t *Ts) MustExec() *Ts {
+	t.Exec()
+	if !t.IsSuccess() {
+		panic(fmt.Sprintf("mesh: command %q exited with code %d: %s (%v)", t.Cfg.Cmd, t.exitCode, t.stderr, t.err))
+	}
+	return t
+}
 
-	var out, stderr bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &stderr
+// sleep 在重试间隔内等待，父 context 被取消或调用 Cancel 时立即返回 false
+func (t *Ts) sleep(d time.Duration) bool {
+	var done <-chan struct{}
+	if t.ctx != nil {
+		done = t.ctx.Done()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-t.canceledCh():
+		return false
+	case <-done:
+		return false
+	}
+}
 
-	err := cmd.Run()
-	if err != nil {
-		t.stderr = err.Error()
+// Start 启动命令但不等待其结束，需要配合 Wait 获取执行结果。
+// 重复调用 Start 不会再次启动进程
+func (t *Ts) Start() *Ts {
+	if t.run != nil {
+		return t
 	}
+	r := t.prepare(false)
+	t.launch()
+	if r.stdoutCh != nil {
+		// 由后台回收并关闭通道，调用方 range 通道即可等到结束，无需先调用 Wait
+		go r.waitOnce.Do(func() { t.wait(r) })
+	}
+	return t
+}
 
-	if cmd.ProcessState != nil {
-		t.exitCode = cmd.ProcessState.ExitCode()
+// prepare 构造本次执行的进程与输出缓冲并保存到 t.run，准备阶段的错误记录在 startErr 中。
+// needStdin 为 true 时 POSIX Shell 改用 -c 传入脚本，把 stdin 留给调用方（如管道的上游）
+func (t *Ts) prepare(needStdin bool) *running {
+	// 这些结果按次记录，不能沿用上次执行（如 DryRun、缓存命中）留下的值
+	t.dryRun = false
+	t.cached = false
+	t.reaped = false
+	t.limitHit = false
+	t.pid = 0
+	t.shellUsed = ""
+	t.invocation = Invocation{}
+
+	parent := t.Context()
+	// Timeout 小于等于 0 表示不限制执行时间，避免直接构造 Config 时零值导致立即超时
+	ctx, cancel := context.WithCancel(parent)
+	if t.Cfg.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, t.Cfg.Timeout)
+	}
+
+	var (
+		name    string
+		args    []string
+		stdin   io.Reader
+		script  string
+		rawLine bool
+		prepErr error
+	)
+	if t.stdin != nil {
+		stdin = t.stdin()
+	}
+	if len(t.Cfg.Args) > 0 {
+		// argv 模式：直接执行程序，Cmd 仅用于展示
+		name, args = t.Cfg.Args[0], t.Cfg.Args[1:]
 	} else {
-		t.exitCode = -1
+		name = t.Cfg.Shell
+		if _, err := exec.LookPath(name); err != nil {
+			prepErr = fmt.Errorf("%w: %s: %w", ErrShellNotFound, t.Cfg.Shell, err)
+			fb := t.Cfg.FallbackShell
+			if fb == "" {
+				fb = fallbackShell()
+			}
+			if fb != "-" && fb != name {
+				if _, fbErr := exec.LookPath(fb); fbErr == nil {
+					name, prepErr = fb, nil
+					t.logFallback(fb)
+				} else {
+					prepErr = fmt.Errorf("%w: neither %s nor fallback %s is available: %w", ErrShellNotFound, t.Cfg.Shell, fb, err)
+				}
+			}
+		}
+		if prepErr == nil {
+			t.shellUsed = name
+			if !isKnownShell(name) {
+				t.logUnknownShell(name)
+			}
+		}
+		// sudo 需要通过 stdin 读取密码，伪终端的 stdin 属于终端，此时脚本改为参数传入
+		var viaStdin bool
+		args, viaStdin = shellArgs(name, t.Cfg.ShellArgs, t.Cfg.Cmd, needStdin || t.Cfg.SudoPassword != "" || t.Cfg.UsePTY || len(t.expects) > 0)
+		if viaStdin {
+			stdin = strings.NewReader(t.Cfg.Cmd)
+			script = t.Cfg.Cmd
+		} else {
+			rawLine = true
+		}
+	}
+	if t.Cfg.Sudo {
+		name, args, stdin = sudoWrap(t.Cfg.SudoPassword, name, args, stdin)
+	}
+	name, args = wrapRlimit(name, args, t.Cfg.RLimitCPU, t.Cfg.RLimitAS)
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = stdin
+	if rawLine && name == t.shellUsed {
+		setCmdLine(cmd, name, t.Cfg.ShellArgs, t.Cfg.Cmd)
+	}
+	// 进程被终止后，最多再等待 1s 让子进程持有的输出管道关闭，避免 Wait 迟迟不返回；
+	// 优雅终止期间不能提前关闭管道，因此需要额外加上 KillGracePeriod
+	cmd.WaitDelay = time.Second + t.Cfg.KillGracePeriod
+	cmd.Env = t.environ()
+	cmd.ExtraFiles = t.Cfg.ExtraFiles
+	cmd.Dir = t.Cfg.Dir
+	if t.Cfg.Chroot != "" {
+		// chroot 不会改变工作目录，必须进入新根目录内部，否则子进程仍可经由 . 访问外部文件
+		cmd.Dir = filepath.Join("/", t.Cfg.Dir)
+	}
+	setProcessGroup(cmd, t.Cfg.KillGracePeriod)
+	t.invocation = Invocation{
+		Path:   cmd.Path,
+		Args:   slices.Clone(cmd.Args),
+		Dir:    cmd.Dir,
+		Shell:  t.shellUsed,
+		Script: script,
+		Stdin:  t.stdin != nil,
+	}
+
+	enc, encErr := lookupEncoding(t.Cfg.Encoding)
+	if prepErr == nil {
+		prepErr = encErr
+	}
+
+	r := &running{cmd: cmd, parent: parent, ctx: ctx, cancel: cancel, enc: enc, done: make(chan struct{})}
+	var onLimit func()
+	if t.Cfg.KillOnOutputLimit {
+		onLimit = func() {
+			r.limitKilled.Store(true)
+			cancel()
+		}
+	}
+	r.stdout = newLimitBuffer(t.Cfg.MaxOutputBytes, t.Cfg.TruncateMode, onLimit)
+	r.stderr = newLimitBuffer(t.Cfg.MaxOutputBytes, t.Cfg.TruncateMode, onLimit)
+	cmd.Stdout = r.stdout
+	cmd.Stderr = r.stderr
+	if t.Cfg.RedirectStderrToStdout && !t.Cfg.Combine {
+		// 与 Combine 相同，共用同一个 Writer 以保留输出顺序
+		shared := newSyncBuffer(r.stdout)
+		cmd.Stdout = shared
+		cmd.Stderr = shared
+	}
+	if t.Cfg.Combine {
+		// 同一个 Writer 会让 os/exec 只创建一个管道，从而保留真实的输出顺序
+		r.combined = newSyncBuffer(newLimitBuffer(t.Cfg.MaxOutputBytes, t.Cfg.TruncateMode, onLimit))
+		cmd.Stdout = r.combined
+		cmd.Stderr = r.combined
+	}
+	// 逐行回调由 os/exec 的拷贝 goroutine 驱动，Wait 返回前这些 goroutine 均已结束
+	onLine := t.onStdoutLine
+	if ch := t.stdoutCh; ch != nil {
+		r.stdoutCh, t.stdoutCh = ch, nil
+		onLine = func(line string) {
+			ch.send(line)
+			if t.onStdoutLine != nil {
+				t.onStdoutLine(line)
+			}
+		}
+	}
+	if onLine != nil {
+		r.stdoutLines = newLineWriter(decodeLine(enc, onLine))
+		cmd.Stdout = io.MultiWriter(cmd.Stdout, r.stdoutLines)
+	}
+	if t.onStderrLine != nil {
+		r.stderrLines = newLineWriter(decodeLine(enc, t.onStderrLine))
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, r.stderrLines)
+	}
+	if len(t.expects) > 0 && prepErr == nil {
+		if t.Cfg.Sudo && t.Cfg.SudoPassword != "" {
+			// sudo 的密码需写在 stdin 开头，无法再由 Expect 接管
+			prepErr = fmt.Errorf("%w: Expect cannot be combined with SudoPassword", ErrInvalidConfig)
+		} else {
+			r.expect, prepErr = newExpecter(t.expects, cancel)
+		}
+		if r.expect != nil {
+			cmd.Stdin = r.expect.in
+			cmd.Stdout = io.MultiWriter(cmd.Stdout, r.expect)
+			cmd.Stderr = io.MultiWriter(cmd.Stderr, r.expect)
+		}
+	}
+	if t.stdoutW != nil {
+		cmd.Stdout = io.MultiWriter(cmd.Stdout, &teeWriter{w: t.stdoutW})
+	}
+	if t.stderrW != nil {
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, &teeWriter{w: t.stderrW})
+	}
+	if d := t.Cfg.IdleTimeout; d > 0 {
+		// 计时器在启动进程前才开始计时，之后每次输出重置
+		r.idle = &idleTimer{Timer: time.AfterFunc(d, func() {
+			r.idleKilled.Store(true)
+			cancel()
+		}), d: d}
+		r.idle.Stop()
+		// stdout 与 stderr 为同一个 Writer 时保持相同，os/exec 才会共用一个管道
+		shared := cmd.Stdout == cmd.Stderr
+		cmd.Stdout = &idleWriter{w: cmd.Stdout, timer: r.idle}
+		if shared {
+			cmd.Stderr = cmd.Stdout
+			r.idle.streams.Store(1)
+		} else {
+			cmd.Stderr = &idleWriter{w: cmd.Stderr, timer: r.idle}
+			r.idle.streams.Store(2)
+		}
+	}
+
+	if prepErr == nil && !t.checked {
+		prepErr = t.checkExec()
+	}
+	if prepErr == nil && (t.Cfg.Nice < -20 || t.Cfg.Nice > 19) {
+		prepErr = fmt.Errorf("%w: nice must be between -20 and 19, got %d", ErrInvalidConfig, t.Cfg.Nice)
+	}
+	if prepErr == nil && t.Cfg.User != "" {
+		prepErr = setCredential(cmd, t.Cfg.User)
+	}
+	if prepErr == nil && t.Cfg.Chroot != "" {
+		prepErr = setChroot(cmd, t.Cfg.Chroot)
 	}
+	if prepErr == nil {
+		prepErr = setNamespaces(cmd, t.Cfg)
+	}
+	if prepErr == nil {
+		prepErr = checkDir(t.Cfg.workDir())
+	}
+	r.startErr = prepErr
+	t.setRun(r)
+	return r
+}
 
-	t.stdout = strings.TrimSpace(out.String())
-	t.stderr = strings.TrimSpace(stderr.String())
+// launch 启动 prepare 构造好的进程
+func (t *Ts) launch() *Ts {
+	r := t.run
+	if t.Cfg.BeforeExec != nil {
+		t.Cfg.BeforeExec(t)
+	}
+	// 限流等待不计入 Duration
+	if l := t.rateLimiter(); l != nil && r.startErr == nil && !t.Cfg.DryRun {
+		if err := l.Wait(r.ctx); err != nil {
+			r.startErr = fmt.Errorf("mesh: rate limit: %w", err)
+		}
+	}
+	t.startedAt = time.Now()
+	if r.startErr != nil {
+		return t
+	}
+	if t.Cfg.DryRun {
+		t.dryRun = true
+		t.logStart()
+		return t
+	}
+	if t.Cfg.UsePTY {
+		r.pty, r.startErr = openPTY(r.cmd)
+		// 伪终端只转发一路输出
+		if r.idle != nil {
+			r.idle.streams.Store(1)
+		}
+	}
+	if r.startErr == nil {
+		if r.idle != nil {
+			r.idle.Reset(t.Cfg.IdleTimeout)
+		}
+		r.startErr = startWithAffinity(r.cmd, t.Cfg.CPUAffinity)
+		if r.startErr != nil && t.Cfg.User != "" && errors.Is(r.startErr, os.ErrPermission) {
+			r.startErr = fmt.Errorf("%w: %s: %w", ErrUserSwitch, t.Cfg.User, r.startErr)
+		} else if r.startErr != nil && t.Cfg.Chroot != "" {
+			// 新根目录下缺少程序时 exec 报 ENOENT，需指明是 chroot 内的问题
+			r.startErr = fmt.Errorf("%w: %s: %w", ErrChroot, t.Cfg.Chroot, r.startErr)
+		} else if ns := t.Cfg.namespaces(); r.startErr != nil && len(ns) > 0 {
+			r.startErr = fmt.Errorf("mesh: new namespace %s: %w", strings.Join(ns, ","), r.startErr)
+		}
+	}
+	if r.startErr == nil && t.Cfg.Nice != 0 {
+		if err := setNice(r.cmd.Process.Pid, t.Cfg.Nice); err != nil {
+			_ = r.cmd.Process.Kill()
+			_ = r.cmd.Wait()
+			r.startErr = fmt.Errorf("mesh: set nice %d: %w", t.Cfg.Nice, err)
+		}
+	}
+	if r.pty != nil {
+		if r.startErr != nil {
+			r.pty.close()
+		} else {
+			r.pty.start()
+		}
+	}
+	if r.startErr == nil {
+		if r.expect != nil {
+			r.expect.start()
+		}
+		t.state = StateRunning
+		t.pid = r.cmd.Process.Pid
+		t.logStart()
+	}
+	return t
+}
 
-	if ctx.Err() == context.DeadlineExceeded {
-		t.stderr = fmt.Sprintf("Error: Command execution timed out after %s.", t.Cfg.Timeout)
+// Detach 启动一个脱离本程序的后台进程后立即返回，不等待也不回收，本程序退出后进程继续运行，PID 通过 Pid 获取。
+// 与 Start 不同：stdin、stdout、stderr 均指向空设备，不捕获任何输出（需要日志时在命令中自行重定向），
+// Timeout、context、Cancel、钩子与重试均不生效。Unix 上进程运行在新会话中并由 init 收养
+func (t *Ts) Detach() error {
+	t.clearResult()
+	r := t.prepare(true)
+	r.cancel()
+	if r.expect != nil {
+		_ = r.expect.stop()
+	}
+	if r.stdoutCh != nil {
+		r.stdoutCh.close()
+	}
+	t.setRun(nil)
+	t.startedAt = time.Now()
+	err := r.startErr
+	if err == nil {
+		t.pid, err = startDetached(r.cmd)
+	}
+	if err != nil {
+		t.err = fmt.Errorf("mesh: detach: %w", err)
 		t.exitCode = -1
+		t.state = StateNotStarted
+		return t.err
 	}
+	t.state = StateRunning
+	return nil
+}
+
+// waitProcess 等待进程退出并回收。context 结束后进程会被终止，但处于不可中断睡眠（D 状态）的进程
+// 可能迟迟无法响应 SIGKILL，此时最多再等待 WaitDelay 加 1s 便放弃，reaped 为 false；
+// 后台 goroutine 会在进程最终退出时完成回收，此前进程可能仍然存在
+func waitProcess(r *running) (reaped bool, err error) {
+	ch := make(chan error, 1)
+	go func() { ch <- r.cmd.Wait() }()
+	select {
+	case err := <-ch:
+		return true, err
+	case <-r.ctx.Done():
+	}
+	timer := time.NewTimer(r.cmd.WaitDelay + time.Second)
+	defer timer.Stop()
+	select {
+	case err := <-ch:
+		return true, err
+	case <-timer.C:
+		return false, fmt.Errorf("mesh: process %d did not exit after being killed", r.cmd.Process.Pid)
+	}
+}
+
+// Wait 等待 Start 启动的命令结束并填充 stdout、stderr 与 exitCode。
+// 未调用 Start 时等价于 Exec；重复或并发调用会等待同一次结果
+func (t *Ts) Wait() *Ts {
+	if t.run == nil {
+		t.Start()
+	}
+	r := t.run
+	r.waitOnce.Do(func() { t.wait(r) })
 	return t
 }
 
+// wait 回收进程并填充结果，由 Wait 保证只执行一次
+func (t *Ts) wait(r *running) {
+	r.waited = true
+	defer close(r.done)
+	defer r.cancel()
+	if t.Cfg.AfterExec != nil {
+		defer t.Cfg.AfterExec(t)
+	}
+
+	err := r.startErr
+	var ps *os.ProcessState
+	if r.idle != nil {
+		defer r.idle.Stop()
+	}
+	if err == nil && !t.dryRun {
+		t.reaped, err = waitProcess(r)
+		if t.reaped {
+			ps = r.cmd.ProcessState
+		}
+		if r.pty != nil {
+			r.pty.wait(r.cmd.WaitDelay)
+		}
+	}
+	var expectErr error
+	if r.expect != nil {
+		expectErr = r.expect.stop()
+	}
+	t.finishedAt = time.Now()
+	t.duration = t.finishedAt.Sub(t.startedAt)
+	t.err = err
+	t.attempts++
+	// 未回收时拷贝 goroutine 仍可能写入，不能再刷新逐行回调的残余内容
+	if r.stdoutLines != nil && t.reaped {
+		r.stdoutLines.flush()
+	}
+	if r.stderrLines != nil && t.reaped {
+		r.stderrLines.flush()
+	}
+	if r.stdoutCh != nil {
+		r.stdoutCh.close()
+	}
+
+	switch {
+	case ps != nil:
+		t.exitCode = ps.ExitCode()
+		t.state = StateExited
+		t.limitHit = cpuLimitKilled(ps, t.Cfg.RLimitCPU)
+	case t.dryRun:
+		t.exitCode = 0
+		t.state = StateExited
+	default:
+		t.exitCode = -1
+		t.state = StateNotStarted
+	}
+
+	t.stdoutRaw = decodeBytes(r.enc, r.stdout.Bytes())
+	t.stderrRaw = decodeBytes(r.enc, r.stderr.Bytes())
+	t.stdout = t.output(string(t.stdoutRaw))
+	t.stderr = t.output(string(t.stderrRaw))
+	if r.combined != nil {
+		t.combined = t.output(string(decodeBytes(r.enc, []byte(r.combined.String()))))
+	}
+	t.truncated = r.stdout.truncated() || r.stderr.truncated() || (r.combined != nil && r.combined.truncated())
+	t.dropped = [2]int64{r.stdout.droppedBytes(), r.stderr.droppedBytes()}
+	if r.combined != nil {
+		t.dropped[0] += r.combined.droppedBytes()
+	}
+
+	// 父 context 结束（包括其自身的截止时间）或调用 Cancel 视为取消，只有 Config.Timeout 到期才算超时
+	canceled := r.parent.Err() != nil || r.canceled.Load()
+	switch {
+	case r.parent.Err() != nil:
+		t.err = fmt.Errorf("%w: %w", ErrCanceled, r.parent.Err())
+		t.exitCode = -1
+		t.state = StateCanceled
+	case r.canceled.Load():
+		t.err = fmt.Errorf("%w: %w", ErrCanceled, context.Canceled)
+		t.exitCode = -1
+		t.state = StateCanceled
+	case errors.Is(r.ctx.Err(), context.DeadlineExceeded) && (r.cmd.Process != nil || errors.Is(err, context.DeadlineExceeded)):
+		// 准备阶段失败时进程从未运行，保留原始错误而不是报告超时
+		t.err = fmt.Errorf("%w after %s: %w", ErrTimeout, t.Cfg.Timeout, r.ctx.Err())
+		t.exitCode = -1
+		t.state = StateTimeout
+	}
+	if r.idleKilled.Load() && !canceled {
+		t.err = fmt.Errorf("%w: no output for %s", ErrIdleTimeout, t.Cfg.IdleTimeout)
+		t.exitCode = -1
+		t.state = StateTimeout
+	}
+	if r.limitKilled.Load() && !canceled {
+		t.err = fmt.Errorf("%w: %d bytes", ErrOutputLimit, t.Cfg.MaxOutputBytes)
+		t.exitCode = -1
+	}
+	if t.limitHit && !canceled {
+		t.err = fmt.Errorf("%w: cpu time %s", ErrLimitExceeded, t.Cfg.RLimitCPU)
+	}
+	if expectErr != nil && !canceled {
+		t.err = expectErr
+		t.exitCode = -1
+	}
+	t.logFinish()
+	t.observe()
+}
+
+// namespaces 返回需要新建的命名空间名称
+func (c *Config) namespaces() []string {
+	var ns []string
+	for _, n := range []struct {
+		on   bool
+		name string
+	}{
+		{c.NewPIDNamespace, "pid"},
+		{c.NewNetNamespace, "net"},
+		{c.NewMountNamespace, "mnt"},
+		{c.NewUTSNamespace, "uts"},
+		{c.NewIPCNamespace, "ipc"},
+	} {
+		if n.on {
+			ns = append(ns, n.name)
+		}
+	}
+	return ns
+}
+
+// workDir 返回工作目录在当前进程视角下的路径，设置了 Chroot 时 Dir 位于新根目录之下
+func (c *Config) workDir() string {
+	if c.Chroot == "" {
+		return c.Dir
+	}
+	return filepath.Join(c.Chroot, c.Dir)
+}
+
+// checkDir 在启动前检查工作目录，避免 Shell 给出难以理解的报错
+func checkDir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidDir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%w: %s is not a directory", ErrInvalidDir, dir)
+	}
+	return nil
+}
+
+// output 按 UsePTY、StripANSI、KeepWhitespace 配置处理捕获到的输出
+func (t *Ts) output(s string) string {
+	if t.Cfg.UsePTY {
+		s = strings.ReplaceAll(s, "\r\n", "\n")
+	}
+	if t.Cfg.StripANSI {
+		s = StripANSI(s)
+	}
+	if !t.Cfg.KeepWhitespace {
+		return strings.TrimSpace(s)
+	}
+	return s
+}
+
 // 状态
 
 func (t *Ts) Stdout() string {
@@ -171,17 +1199,292 @@ func (t *Ts) Stderr() string {
 	return t.stderr
 }
 
+// Err 返回执行过程中的 Go 层错误，命令成功时为 nil。
+// 非零退出时为 *exec.ExitError，超时、取消、Shell 缺失与工作目录无效
+// 可分别通过 errors.Is 与 ErrTimeout、ErrCanceled、ErrShellNotFound、ErrInvalidDir 判断
+func (t *Ts) Err() error {
+	return t.err
+}
+
+// Output 执行命令并返回 Stdout，失败时返回 *ExitError，用法与 exec.Cmd.Output 一致
+func (t *Ts) Output() (string, error) {
+	t.Exec()
+	return t.Stdout(), t.AsError()
+}
+
+// CombinedOutput 以 Combine 方式执行命令，返回按输出顺序交织的 stdout 与 stderr，
+// 用法与 exec.Cmd.CombinedOutput 一致。只在本次执行中使用配置的副本开启 Combine，不修改 Cfg
+func (t *Ts) CombinedOutput() (string, error) {
+	cfg := t.Cfg
+	t.Cfg = cfg.clone()
+	t.Cfg.Combine = true
+	t.Exec()
+	t.Cfg = cfg
+	return t.Combined(), t.AsError()
+}
+
+// AsError 成功时返回 nil，否则返回 *ExitError，可通过 errors.As 取得退出码，
+// 也可通过 errors.Is 判断 ErrTimeout 等底层错误
+func (t *Ts) AsError() error {
+	if t.IsSuccess() {
+		return nil
+	}
+	return &ExitError{
+		Cmd:      t.Cfg.Cmd,
+		ExitCode: t.exitCode,
+		Stderr:   t.stderr,
+		Duration: t.duration,
+		Err:      t.err,
+	}
+}
+
+// IsSuccess 命令是否以退出码 0（或 SuccessExitCodes 中的退出码）成功结束
+func (t *Ts) IsSuccess() bool {
+	if t.exitCode == 0 && len(t.Cfg.SuccessExitCodes) == 0 {
+		return t.err == nil
+	}
+	if !t.Cfg.successCode(t.exitCode) {
+		return false
+	}
+	// 非零退出时 err 为 *exec.ExitError，超时、资源限制等其他错误仍视为失败
+	_, exitErr := t.err.(*exec.ExitError)
+	return t.err == nil || exitErr
+}
+
+// successCode 判断退出码是否属于 SuccessExitCodes，为空时仅 0
+func (c *Config) successCode(code int) bool {
+	if len(c.SuccessExitCodes) == 0 {
+		return code == 0
+	}
+	return slices.Contains(c.SuccessExitCodes, code)
+}
+
+// Failed 命令是否失败，包括非零退出、超时、取消以及未能启动
+func (t *Ts) Failed() bool {
+	return !t.IsSuccess()
+}
+
+// IsTimeout 命令是否因超过 Config.Timeout 被终止
+func (t *Ts) IsTimeout() bool {
+	return t.state == StateTimeout
+}
+
+// State 返回命令的执行状态。超时、取消与未能启动的退出码都是 -1，可通过 State 区分
+func (t *Ts) State() State {
+	return t.state
+}
+
+// StartedAt 返回最近一次执行的开始时间，未执行时为零值
+func (t *Ts) StartedAt() time.Time {
+	return t.startedAt
+}
+
+// FinishedAt 返回最近一次执行的结束时间，超时或失败时同样会记录
+func (t *Ts) FinishedAt() time.Time {
+	return t.finishedAt
+}
+
+// LimitExceeded 进程是否因超过 RLimitCPU 被终止
+func (t *Ts) LimitExceeded() bool {
+	return t.limitHit
+}
+
+// Reaped 最近一次启动的进程是否已退出并被回收。context 结束后进程长时间无法终止（如处于 D 状态）时，
+// Exec 不再等待而是直接返回，此时为 false，进程可能仍在运行，OnStdoutLine 等回调也可能在返回后继续被调用
+func (t *Ts) Reaped() bool {
+	return t.reaped
+}
+
+// ShellUsed 返回实际执行脚本的 Shell，Shell 不可用而回退时为 FallbackShell；argv 模式或未执行时为空
+func (t *Ts) ShellUsed() string {
+	return t.shellUsed
+}
+
+// Pid 返回子进程的 PID，进程未启动时为 0；进程结束后仍保留原值
+func (t *Ts) Pid() int {
+	return t.pid
+}
+
+// WaitTimeout 最多等待 d 让 Start 启动的命令结束，超时返回 ErrStillRunning 且不会终止进程，可再次调用继续等待。
+// 命令已结束时成功返回 nil，失败返回与 Output 相同的错误；返回 ErrStillRunning 时不应读取结果
+func (t *Ts) WaitTimeout(d time.Duration) error {
+	if t.run == nil {
+		return ErrNotRunning
+	}
+	r := t.run
+	select {
+	case <-r.done:
+		return t.AsError()
+	default:
+	}
+	go t.Wait()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-r.done:
+		return t.AsError()
+	case <-timer.C:
+		return ErrStillRunning
+	}
+}
+
+// Cancel 终止 Start 启动的进程，效果与取消父 context 相同：State 为 StateCanceled，Err 包含 ErrCanceled。
+// 可在其他 goroutine 中调用；取消请求会保留到 Reset，在进程启动前或重试间隔中调用时，
+// 之后的执行与重试都会立即以取消结束
+func (t *Ts) Cancel() {
+	runMu.Lock()
+	defer runMu.Unlock()
+	if !t.cancelReq {
+		t.cancelReq = true
+		if t.cancelCh != nil {
+			close(t.cancelCh)
+		}
+	}
+	if r := t.run; r != nil {
+		r.canceled.Store(true)
+		r.cancel()
+	}
+}
+
+// Signal 向 Start 启动的进程发送信号，如 syscall.SIGHUP 让其重新加载配置。
+// 信号只发给进程本身而非整个进程组；进程未启动或已退出时返回 ErrNotRunning。
+// Windows 上仅支持 os.Kill，其余信号会返回错误
+func (t *Ts) Signal(sig os.Signal) error {
+	runMu.Lock()
+	r := t.run
+	runMu.Unlock()
+	if r == nil || r.cmd.Process == nil {
+		return ErrNotRunning
+	}
+	if err := r.cmd.Process.Signal(sig); err != nil {
+		if errors.Is(err, os.ErrProcessDone) {
+			return fmt.Errorf("%w: %w", ErrNotRunning, err)
+		}
+		return fmt.Errorf("mesh: signal %s: %w", sig, err)
+	}
+	return nil
+}
+
+// OutputTruncated 输出是否因超过 MaxOutputBytes 被截断
+func (t *Ts) OutputTruncated() bool {
+	return t.truncated
+}
+
+// StdoutDropped 返回 stdout 因超过 MaxOutputBytes 被丢弃的字节数，开启 Combine 时包含合并输出丢弃的部分
+func (t *Ts) StdoutDropped() int64 {
+	return t.dropped[0]
+}
+
+// StderrDropped 返回 stderr 因超过 MaxOutputBytes 被丢弃的字节数
+func (t *Ts) StderrDropped() int64 {
+	return t.dropped[1]
+}
+
+// Cached 结果是否来自 CacheTTL 缓存而非本次实际执行
+func (t *Ts) Cached() bool {
+	return t.cached
+}
+
+// Attempts 返回最近一次 Exec 实际执行的次数，包含重试
+func (t *Ts) Attempts() int {
+	return t.attempts
+}
+
+// Combined 返回按输出顺序交织的 stdout 与 stderr，仅在开启 Combine 时有内容。
+// 合并模式下两个流写入同一缓冲区，Stdout 为空，Stderr 只包含执行出错时的错误信息
+func (t *Ts) Combined() string {
+	return t.combined
+}
+
+// StdoutRaw 返回未经 TrimSpace 处理的原始 stdout
+func (t *Ts) StdoutRaw() string {
+	return string(t.stdoutRaw)
+}
+
+// StderrRaw 返回未经 TrimSpace 处理的原始 stderr
+func (t *Ts) StderrRaw() string {
+	return string(t.stderrRaw)
+}
+
+// StdoutBytes 返回原始 stdout 字节，适合二进制或非 UTF-8 输出，调用方不应修改返回的切片
+func (t *Ts) StdoutBytes() []byte {
+	return t.stdoutRaw
+}
+
+// StderrBytes 返回原始 stderr 字节，调用方不应修改返回的切片
+func (t *Ts) StderrBytes() []byte {
+	return t.stderrRaw
+}
+
+// StdoutHash 返回原始 stdout 字节的 SHA-256（十六进制），不受去除首尾空白等处理影响，
+// 可在不保存完整输出的情况下判断两次执行的输出是否变化
+func (t *Ts) StdoutHash() string {
+	sum := sha256.Sum256(t.stdoutRaw)
+	return hex.EncodeToString(sum[:])
+}
+
+// StdoutReader 返回读取 stdout 的 io.Reader，可直接交给 json.Decoder 等使用
+func (t *Ts) StdoutReader() io.Reader {
+	return strings.NewReader(t.stdout)
+}
+
+// StderrReader 返回读取 stderr 的 io.Reader
+func (t *Ts) StderrReader() io.Reader {
+	return strings.NewReader(t.stderr)
+}
+
 func (t *Ts) ExitCode() int {
 	return t.exitCode
 }
 
+// Duration 返回命令执行耗时，包含超时被终止的情况
+func (t *Ts) Duration() time.Duration {
+	return t.duration
+}
+
 func (t *Ts) Show() map[string]any {
 	ret := map[string]any{
-		"stdout":   t.stdout,
-		"stderr":   t.stderr,
-		"exitCode": t.exitCode,
-		"envVars":  t.Cfg.Env,
-		"cmdStr":   t.Cfg.Cmd,
+		"stdout":     t.stdout,
+		"stderr":     t.stderr,
+		"exitCode":   t.exitCode,
+		"error":      errString(t.err),
+		"state":      t.state.String(),
+		"envVars":    t.maskedEnv(),
+		"cmdStr":     t.Cfg.Cmd,
+		"dir":        t.Cfg.Dir,
+		"sudo":       t.Cfg.Sudo,
+		"duration":   t.duration,
+		"startedAt":  t.startedAt,
+		"finishedAt": t.finishedAt,
+		"attempts":   t.attempts,
+		"pid":        t.pid,
+		"dryRun":     t.dryRun,
+		"cached":     t.cached,
+	}
+	if t.dryRun {
+		ret["plan"] = t.plan()
 	}
 	return ret
 }
+
+// plan 返回解析后的完整调用方式，用于演练模式的展示
+func (t *Ts) plan() map[string]any {
+	plan := map[string]any{
+		"envVars":  t.maskedEnv(),
+		"cmdStr":   t.Cfg.Cmd,
+		"expanded": expandEnv(t.Cfg.Cmd, t.maskedEnv()),
+	}
+	if t.run != nil {
+		plan["path"] = t.run.cmd.Path
+		plan["args"] = t.run.cmd.Args
+		plan["dir"] = t.run.cmd.Dir
+	}
+	return plan
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,82 @@
+// Package meshtest 提供 mesh 的测试替身，用于在不派生真实进程的情况下测试上层代码
+package meshtest
+
+import (
+	"sync"
+
+	"github.com/lwmacct/250300-go-mod-mesh/pkg/mesh"
+)
+
+// FakeExecutor 按命令字符串返回预设结果，实现 mesh.Commander。
+// 未预设的命令返回退出码 127，并发安全
+type FakeExecutor struct {
+	mu      sync.Mutex
+	results map[string]mesh.Result
+	calls   []string
+}
+
+// NewFakeExecutor 创建空的 FakeExecutor
+func NewFakeExecutor() *FakeExecutor {
+	return &FakeExecutor{results: make(map[string]mesh.Result)}
+}
+
+// On 为命令预设完整结果
+func (f *FakeExecutor) On(cmdStr string, r mesh.Result) *FakeExecutor {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r.Cmd = cmdStr
+	f.results[cmdStr] = r
+	return f
+}
+
+// OnOutput 为命令预设 stdout、stderr 和退出码
+func (f *FakeExecutor) OnOutput(cmdStr, stdout, stderr string, exitCode int) *FakeExecutor {
+	return f.On(cmdStr, mesh.Result{Stdout: stdout, Stderr: stderr, ExitCode: exitCode})
+}
+
+// Command 返回执行时回放预设结果的 Executor
+func (f *FakeExecutor) Command(cmdStr string) mesh.Executor {
+	return &fakeCmd{f: f, cmd: cmdStr}
+}
+
+// Calls 返回已执行的命令，按调用顺序排列
+func (f *FakeExecutor) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+// Reset 清空预设结果和调用记录
+func (f *FakeExecutor) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	clear(f.results)
+	f.calls = nil
+}
+
+func (f *FakeExecutor) exec(cmdStr string) *mesh.Ts {
+	f.mu.Lock()
+	f.calls = append(f.calls, cmdStr)
+	r, ok := f.results[cmdStr]
+	f.mu.Unlock()
+	if !ok {
+		r = mesh.Result{
+			Cmd:      cmdStr,
+			Stderr:   "meshtest: no result for command: " + cmdStr,
+			ExitCode: 127,
+			Error:    "meshtest: unexpected command",
+		}
+	}
+	return mesh.FromResult(r)
+}
+
+type fakeCmd struct {
+	f   *FakeExecutor
+	cmd string
+}
+
+func (c *fakeCmd) Exec() *mesh.Ts {
+	return c.f.exec(c.cmd)
+}
+
+var _ mesh.Commander = (*FakeExecutor)(nil)
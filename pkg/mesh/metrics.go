@@ -0,0 +1,110 @@
+package mesh
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Metrics 收集执行指标，每次进程运行结束（含每次重试）时以结果调用 Observe，
+// 需要并发安全；Config.Metrics 为 nil 时不产生任何开销，DryRun 不计入
+type Metrics interface {
+	Observe(t *Ts)
+}
+
+// SetMetrics 设置指标收集器，多个 Ts 可共用同一个收集器
+func (t *Ts) SetMetrics(m Metrics) *Ts {
+	t.Cfg.Metrics = m
+	return t
+}
+
+func (t *Ts) observe() {
+	if t.Cfg.Metrics == nil || t.dryRun {
+		return
+	}
+	t.Cfg.Metrics.Observe(t)
+}
+
+// DefaultBuckets 是 MemoryMetrics 默认的耗时直方图上界
+var DefaultBuckets = []time.Duration{
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+}
+
+// MetricsSnapshot 是 MemoryMetrics 某一时刻的计数。
+// Success、Failure、Timeout、Canceled 互不重叠，之和等于 Total；
+// Counts[i] 为耗时不超过 Buckets[i] 的次数（不累加），最后一项为超过所有上界的次数
+type MetricsSnapshot struct {
+	Total         int64
+	Success       int64
+	Failure       int64
+	Timeout       int64
+	Canceled      int64
+	TotalDuration time.Duration
+	Buckets       []time.Duration
+	Counts        []int64
+}
+
+// MemoryMetrics 是基于内存的 Metrics 实现
+type MemoryMetrics struct {
+	mu      sync.Mutex
+	buckets []time.Duration
+	snap    MetricsSnapshot
+}
+
+// NewMemoryMetrics 创建内存指标收集器，buckets 为直方图上界，为空时使用 DefaultBuckets
+func NewMemoryMetrics(buckets ...time.Duration) *MemoryMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	m := &MemoryMetrics{buckets: buckets}
+	m.snap.Counts = make([]int64, len(buckets)+1)
+	return m
+}
+
+// Observe 按执行结果累加计数
+func (m *MemoryMetrics) Observe(t *Ts) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := &m.snap
+	s.Total++
+	switch {
+	case t.state == StateTimeout:
+		s.Timeout++
+	case t.state == StateCanceled:
+		s.Canceled++
+	case t.IsSuccess():
+		s.Success++
+	default:
+		s.Failure++
+	}
+	s.TotalDuration += t.duration
+	i, _ := slices.BinarySearch(m.buckets, t.duration)
+	s.Counts[i]++
+}
+
+// Snapshot 返回当前计数的副本
+func (m *MemoryMetrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.snap
+	s.Buckets = slices.Clone(m.buckets)
+	s.Counts = slices.Clone(m.snap.Counts)
+	return s
+}
+
+// Reset 清零所有计数
+func (m *MemoryMetrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.snap = MetricsSnapshot{Counts: make([]int64, len(m.buckets)+1)}
+}
@@ -0,0 +1,37 @@
+//go:build linux
+
+package mesh
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setNamespaces 让子进程在 c 指定的新命名空间中运行。PID 命名空间只能在 clone 时创建，
+// 挂载命名空间通过 Unshareflags 创建，Go 会随后将 / 设为私有传播，避免挂载泄漏到宿主
+func setNamespaces(cmd *exec.Cmd, c *Config) error {
+	var flags uintptr
+	if c.NewPIDNamespace {
+		flags |= syscall.CLONE_NEWPID
+	}
+	if c.NewNetNamespace {
+		flags |= syscall.CLONE_NEWNET
+	}
+	if c.NewUTSNamespace {
+		flags |= syscall.CLONE_NEWUTS
+	}
+	if c.NewIPCNamespace {
+		flags |= syscall.CLONE_NEWIPC
+	}
+	if flags == 0 && !c.NewMountNamespace {
+		return nil
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Cloneflags |= flags
+	if c.NewMountNamespace {
+		cmd.SysProcAttr.Unshareflags |= syscall.CLONE_NEWNS
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package mesh
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// setNamespaces 在非 Linux 平台上不支持命名空间，为避免误以为已隔离，直接返回错误
+func setNamespaces(cmd *exec.Cmd, c *Config) error {
+	if ns := c.namespaces(); len(ns) > 0 {
+		return fmt.Errorf("mesh: new namespace %s: not supported on this platform", strings.Join(ns, ","))
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+package mesh
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ansiRe 匹配 CSI 序列（如颜色 \x1b[31m）、以 BEL 或 ST 结尾的 OSC 序列（如终端标题）、字符集切换以及单字符转义
+var ansiRe = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[()*+][ -~]|\x1b[@-Z\\-_]`)
+
+// StripANSI 去除 s 中的 ANSI 转义序列
+func StripANSI(s string) string {
+	if !strings.Contains(s, "\x1b") {
+		return s
+	}
+	return ansiRe.ReplaceAllString(s, "")
+}
+
+// TruncateMode 决定输出超过 MaxOutputBytes 后保留哪一部分
+type TruncateMode int
+
+const (
+	TruncateHead TruncateMode = iota // 保留开头，丢弃之后的输出
+	TruncateTail                     // 保留末尾，通常包含最终的错误信息
+)
+
+// String 返回截断模式的名称 head 或 tail
+func (m TruncateMode) String() string {
+	switch m {
+	case TruncateHead:
+		return "head"
+	case TruncateTail:
+		return "tail"
+	default:
+		return fmt.Sprintf("TruncateMode(%d)", int(m))
+	}
+}
+
+// MarshalText 以名称形式序列化截断模式
+func (m TruncateMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText 从 head 或 tail 解析截断模式，便于从配置文件读取
+func (m *TruncateMode) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "head":
+		*m = TruncateHead
+	case "tail":
+		*m = TruncateTail
+	default:
+		return fmt.Errorf("mesh: unknown truncate mode %q", text)
+	}
+	return nil
+}
+
+// limitBuffer 是可限制容量的输出缓冲，limit <= 0 表示不限制。
+// 超出容量后按 mode 保留开头或末尾，首次超限时调用 onLimit；
+// 保留末尾时使用容量固定为 limit 的环形缓冲，新数据覆盖最早的数据，不会反复搬移内存。
+// 进程未能回收时 os/exec 的拷贝 goroutine 可能仍在写入，因此读写均需加锁
+type limitBuffer struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	ring    []byte
+	start   int // ring 写满后最早数据的位置
+	limit   int
+	mode    TruncateMode
+	dropped int64
+	onLimit func()
+}
+
+func newLimitBuffer(limit int, mode TruncateMode, onLimit func()) *limitBuffer {
+	return &limitBuffer{limit: limit, mode: mode, onLimit: onLimit}
+}
+
+// Write 始终报告全部写入成功，避免子进程因管道阻塞或 SIGPIPE 提前退出
+func (b *limitBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := len(p)
+	switch {
+	case b.limit <= 0:
+		return b.buf.Write(p)
+	case b.mode == TruncateTail:
+		b.writeRing(p)
+	default:
+		if room := b.limit - b.buf.Len(); len(p) > room {
+			b.drop(len(p) - room)
+			p = p[:room]
+		}
+		b.buf.Write(p)
+	}
+	return n, nil
+}
+
+// writeRing 写入环形缓冲：未满时顺序追加，写满后从 start 处覆盖最早的数据
+func (b *limitBuffer) writeRing(p []byte) {
+	if len(p) >= b.limit {
+		b.drop(len(b.ring) + len(p) - b.limit)
+		b.ring = append(b.ring[:0], p[len(p)-b.limit:]...)
+		b.start = 0
+		return
+	}
+	if room := b.limit - len(b.ring); room > 0 {
+		k := min(room, len(p))
+		b.ring = append(b.ring, p[:k]...)
+		p = p[k:]
+	}
+	b.drop(len(p))
+	for len(p) > 0 {
+		k := copy(b.ring[b.start:], p)
+		p = p[k:]
+		b.start = (b.start + k) % b.limit
+	}
+}
+
+func (b *limitBuffer) drop(n int) {
+	if n <= 0 {
+		return
+	}
+	if b.dropped == 0 && b.onLimit != nil {
+		b.onLimit()
+	}
+	b.dropped += int64(n)
+}
+
+// Bytes 返回当前内容，环形缓冲会被后续写入原地覆盖，因此总是返回副本
+func (b *limitBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.limit <= 0 || b.mode != TruncateTail {
+		return b.buf.Bytes()
+	}
+	out := make([]byte, 0, len(b.ring))
+	out = append(out, b.ring[b.start:]...)
+	return append(out, b.ring[:b.start]...)
+}
+
+func (b *limitBuffer) String() string {
+	return string(b.Bytes())
+}
+
+func (b *limitBuffer) truncated() bool {
+	return b.droppedBytes() > 0
+}
+
+func (b *limitBuffer) droppedBytes() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dropped
+}
+
+// syncBuffer 是并发安全的输出缓冲，stdout 与 stderr 同时写入时保证按到达顺序追加
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf *limitBuffer
+}
+
+func newSyncBuffer(buf *limitBuffer) *syncBuffer {
+	return &syncBuffer{buf: buf}
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *syncBuffer) truncated() bool {
+	return b.buf.truncated()
+}
+
+func (b *syncBuffer) droppedBytes() int64 {
+	return b.buf.droppedBytes()
+}
+
+// lineWriter 将写入的数据按行切分并回调 fn，未以换行结尾的残余内容在 flush 时回调
+type lineWriter struct {
+	fn  func(string)
+	buf []byte
+}
+
+func newLineWriter(fn func(string)) *lineWriter {
+	return &lineWriter{fn: fn}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) flush() {
+	if len(w.buf) > 0 {
+		w.emit(w.buf)
+		w.buf = nil
+	}
+}
+
+// emit 回调单行内容，回调中的 panic 会被吞掉，避免拖垮整个进程
+func (w *lineWriter) emit(line []byte) {
+	defer func() { _ = recover() }()
+	w.fn(string(bytes.TrimSuffix(line, []byte{'\r'})))
+}
+
+// idleTimer 是 IdleTimeout 计时器，每次输出重置，所有输出流读到 EOF 后停止，
+// 进程正常退出后即使很久才调用 Wait 也不会被判为空闲
+type idleTimer struct {
+	*time.Timer
+	d       time.Duration
+	streams atomic.Int32
+}
+
+// eof 记录一个输出流结束，全部结束时停止计时
+func (t *idleTimer) eof() {
+	if t.streams.Add(-1) == 0 {
+		t.Stop()
+	}
+}
+
+// idleWriter 在每次写入时重置 IdleTimeout 计时器
+type idleWriter struct {
+	w     io.Writer
+	timer *idleTimer
+}
+
+func (w *idleWriter) Write(p []byte) (int, error) {
+	w.timer.Reset(w.timer.d)
+	return w.w.Write(p)
+}
+
+// ReadFrom 使 io.Copy 读到 EOF 时通知计时器，os/exec 与伪终端都通过 io.Copy 写入输出
+func (w *idleWriter) ReadFrom(r io.Reader) (int64, error) {
+	defer w.timer.eof()
+	return io.Copy(struct{ io.Writer }{w}, r)
+}
+
+// stdoutChanSize 是 StdoutChan 通道的缓冲行数
+const stdoutChanSize = 64
+
+// lineChan 是 StdoutChan 使用的有界通道。进程未能回收时拷贝 goroutine 可能仍在发送，
+// close 先通过 stop 解除其阻塞，再在锁内关闭通道，避免向已关闭的通道发送
+type lineChan struct {
+	ch       chan string
+	stop     chan struct{}
+	stopOnce sync.Once
+	mu       sync.Mutex
+	closed   bool
+}
+
+func newLineChan() *lineChan {
+	return &lineChan{ch: make(chan string, stdoutChanSize), stop: make(chan struct{})}
+}
+
+func (c *lineChan) send(line string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.ch <- line:
+	case <-c.stop:
+	}
+}
+
+func (c *lineChan) close() {
+	c.stopOnce.Do(func() { close(c.stop) })
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.ch)
+	}
+}
+
+// teeWriter 把输出同步写给调用方提供的 Writer，写入失败后不再写入，但不影响输出的捕获
+type teeWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (w *teeWriter) Write(p []byte) (int, error) {
+	if w.err == nil {
+		_, w.err = w.w.Write(p)
+	}
+	return len(p), nil
+}
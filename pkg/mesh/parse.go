@@ -0,0 +1,320 @@
+package mesh
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ToMapDelim 将每行按第一个 sep 切分为键值对，键与值会去掉首尾空白，
+// 适合 KEY=VALUE、key: value 这类输出。不含 sep 的行会被跳过，重复的键以最后一行为准
+func (t *Ts) ToMapDelim(sep string) map[string]string {
+	lines := t.Lines()
+	data := make(map[string]string, len(lines))
+	for _, line := range lines {
+		k, v, ok := strings.Cut(line, sep)
+		if !ok {
+			continue
+		}
+		data[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return data
+}
+
+// ToMapLine 以每行第一个空白分隔的字段为键，行的剩余部分（保留内部空白）为值，
+// 适合 /etc/hosts 这类值为自由文本的输出；只有键的行值为空字符串
+func (t *Ts) ToMapLine() map[string]string {
+	lines := t.Lines()
+	data := make(map[string]string, len(lines))
+	for _, line := range lines {
+		fields := fieldsN(line, 2)
+		switch len(fields) {
+		case 1:
+			data[fields[0]] = ""
+		case 2:
+			data[fields[0]] = fields[1]
+		}
+	}
+	return data
+}
+
+// Grep 返回匹配正则 pattern 的 stdout 行
+func (t *Ts) Grep(pattern string) ([]string, error) {
+	return t.grep(pattern, false)
+}
+
+// GrepV 返回不匹配正则 pattern 的 stdout 行，等价于 grep -v
+func (t *Ts) GrepV(pattern string) ([]string, error) {
+	return t.grep(pattern, true)
+}
+
+// GrepFields 返回匹配 pattern 的行按空白切分后的字段
+func (t *Ts) GrepFields(pattern string) ([][]string, error) {
+	lines, err := t.Grep(pattern)
+	if err != nil {
+		return nil, err
+	}
+	result := make([][]string, 0, len(lines))
+	for _, line := range lines {
+		result = append(result, strings.Fields(line))
+	}
+	return result, nil
+}
+
+func (t *Ts) grep(pattern string, invert bool) ([]string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("mesh: invalid pattern: %w", err)
+	}
+	matched := []string{}
+	for _, line := range t.Lines() {
+		if re.MatchString(line) != invert {
+			matched = append(matched, line)
+		}
+	}
+	return matched, nil
+}
+
+// CountLines 返回 stdout 的行数，即 len(Lines())
+func (t *Ts) CountLines() int {
+	return len(t.Lines())
+}
+
+// CountMatches 返回 stdout 中匹配正则 pattern 的行数，相当于 grep -c
+func (t *Ts) CountMatches(pattern string) (int, error) {
+	lines, err := t.grep(pattern, false)
+	return len(lines), err
+}
+
+// Head 返回 Lines 的前 n 行，不足 n 行时返回全部
+func (t *Ts) Head(n int) []string {
+	lines := t.Lines()
+	return lines[:min(max(n, 0), len(lines))]
+}
+
+// Tail 返回 Lines 的最后 n 行，适合配合 TruncateTail 查看日志末尾
+func (t *Ts) Tail(n int) []string {
+	lines := t.Lines()
+	return lines[len(lines)-min(max(n, 0), len(lines)):]
+}
+
+// Column 返回每行按空白切分后第 index 列（从 0 开始）的值，相当于 awk '{print $(index+1)}'；
+// 该行列数不足或 index 为负时取空字符串
+func (t *Ts) Column(index int) []string {
+	lines := t.Lines()
+	col := make([]string, 0, len(lines))
+	for _, line := range lines {
+		var v string
+		if fields := strings.Fields(line); index >= 0 && index < len(fields) {
+			v = fields[index]
+		}
+		col = append(col, v)
+	}
+	return col
+}
+
+// FieldInt 将第 row 行（从 0 开始，同 Lines）按空白切分后的第 col 列解析为十进制整数，
+// 行列不存在或转换失败时返回包含位置与原始值的错误
+func (t *Ts) FieldInt(row, col int) (int64, error) {
+	s, err := t.field(row, col)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("mesh: row %d column %d: %w", row, col, err)
+	}
+	return n, nil
+}
+
+// FieldFloat 与 FieldInt 相同，但解析为浮点数
+func (t *Ts) FieldFloat(row, col int) (float64, error) {
+	s, err := t.field(row, col)
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("mesh: row %d column %d: %w", row, col, err)
+	}
+	return f, nil
+}
+
+// FieldBool 与 FieldInt 相同，但解析为 bool，忽略大小写接受 true/false、yes/no、y/n、on/off 与 1/0
+func (t *Ts) FieldBool(row, col int) (bool, error) {
+	s, err := t.field(row, col)
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(s) {
+	case "true", "yes", "y", "on", "1":
+		return true, nil
+	case "false", "no", "n", "off", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("mesh: row %d column %d: %q is not a boolean", row, col, s)
+}
+
+// field 返回第 row 行第 col 列的值
+func (t *Ts) field(row, col int) (string, error) {
+	lines := t.Lines()
+	if row < 0 || row >= len(lines) {
+		return "", fmt.Errorf("mesh: row %d out of range (%d rows)", row, len(lines))
+	}
+	fields := strings.Fields(lines[row])
+	if col < 0 || col >= len(fields) {
+		return "", fmt.Errorf("mesh: row %d column %d out of range (%d columns)", row, col, len(fields))
+	}
+	return fields[col], nil
+}
+
+// Table 将首个非空行作为表头，其余每行按表头列名映射为 map，适合 df、docker ps 这类输出。
+// 每行最多切分为表头的列数，多出的内容归入最后一列，因此末列（如 COMMAND）可以包含空格；
+// 表头本身包含空格时（如 df 的 "Mounted on"）可通过 maxColumns 指定实际列数。
+// 缺少的列取空字符串，stdout 为空时返回 ErrEmptyOutput
+func (t *Ts) Table(maxColumns ...int) ([]map[string]string, error) {
+	lines := t.Lines()
+	start := 0
+	for start < len(lines) && strings.TrimSpace(lines[start]) == "" {
+		start++
+	}
+	if start == len(lines) {
+		return nil, ErrEmptyOutput
+	}
+
+	limit := -1
+	if len(maxColumns) > 0 && maxColumns[0] > 0 {
+		limit = maxColumns[0]
+	}
+	header := fieldsN(lines[start], limit)
+
+	rows := make([]map[string]string, 0, len(lines)-start-1)
+	for _, line := range lines[start+1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := fieldsN(line, len(header))
+		row := make(map[string]string, len(header))
+		for i, name := range header {
+			if i < len(fields) {
+				row[name] = fields[i]
+			} else {
+				row[name] = ""
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// fieldsN 按空白切分 s，最多返回 n 个字段，最后一个字段保留剩余内容的原始间隔；n < 0 时不限制
+func fieldsN(s string, n int) []string {
+	var fields []string
+	s = strings.TrimSpace(s)
+	for s != "" && (n < 0 || len(fields) < n-1) {
+		i := strings.IndexFunc(s, unicode.IsSpace)
+		if i < 0 {
+			break
+		}
+		fields = append(fields, s[:i])
+		s = strings.TrimLeftFunc(s[i:], unicode.IsSpace)
+	}
+	if s != "" {
+		fields = append(fields, s)
+	}
+	return fields
+}
+
+// ScanStruct 将按空白分列的 stdout 逐行解析为 T，T 的字段通过 `mesh:"列序号"` 指定对应的列（从 0 开始）。
+// 支持 string、整数、浮点数与 bool 字段；行中缺少的列保持零值，类型转换失败时返回包含行列位置的错误
+//
+//	type row struct {
+//		PID  int    `mesh:"1"`
+//		Name string `mesh:"0"`
+//	}
+//	rows, err := mesh.ScanStruct[row](mesh.New("ps -eo comm,pid --no-headers").Exec())
+func ScanStruct[T any](t *Ts) ([]T, error) {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("mesh: ScanStruct requires a struct type, got %s", typ)
+	}
+	cols, err := structColumns(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := t.Lines()
+	rows := make([]T, 0, len(lines))
+	for n, line := range lines {
+		fields := strings.Fields(line)
+		var row T
+		v := reflect.ValueOf(&row).Elem()
+		for fi, col := range cols {
+			if col < 0 || col >= len(fields) {
+				continue
+			}
+			if err := setField(v.Field(fi), fields[col]); err != nil {
+				return nil, fmt.Errorf("mesh: line %d column %d (%s): %w", n+1, col, typ.Field(fi).Name, err)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// structColumns 返回每个字段对应的列序号，未设置 mesh 标签的字段为 -1
+func structColumns(typ reflect.Type) ([]int, error) {
+	cols := make([]int, typ.NumField())
+	for i := range cols {
+		cols[i] = -1
+		f := typ.Field(i)
+		tag, ok := f.Tag.Lookup("mesh")
+		if !ok || tag == "-" || !f.IsExported() {
+			continue
+		}
+		col, err := strconv.Atoi(tag)
+		if err != nil || col < 0 {
+			return nil, fmt.Errorf("mesh: field %s has invalid column tag %q", f.Name, tag)
+		}
+		cols[i] = col
+	}
+	return cols, nil
+}
+
+// setField 将字符串转换为字段对应的基础类型并赋值
+func setField(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package mesh
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Pipeline 将多个命令串联为管道，前一个命令的 stdout 直接连接到后一个命令的 stdin。
+// 返回的 Ts 汇总整条管道：Stdout 为最后一个命令的输出；任一命令失败时 ExitCode、Err
+// 取第一个失败的命令，Stderr 标明失败的阶段。各阶段的 Ts 也会被填充，可单独检查；
+// 中间阶段的 stdout 直接进入管道，不会被捕获。下游提前退出导致上游收到 SIGPIPE 不视为失败
+func Pipeline(stages ...*Ts) *Ts {
+	if len(stages) == 0 {
+		return &Ts{Cfg: NewConfig(), exitCode: -1, state: StateNotStarted, err: errors.New("mesh: empty pipeline")}
+	}
+
+	names := make([]string, len(stages))
+	for i, st := range stages {
+		names[i] = st.Cfg.Cmd
+	}
+	cfg := stages[len(stages)-1].Cfg.clone()
+	cfg.Cmd = strings.Join(names, " | ")
+	result := &Ts{Cfg: cfg}
+
+	result.startedAt = time.Now()
+	var upstream *os.File
+	for i, st := range stages {
+		st.clearResult()
+		r := st.prepare(i > 0)
+		if upstream != nil {
+			r.cmd.Stdin = upstream
+		}
+		var pw, next *os.File
+		if i < len(stages)-1 {
+			pr, w, err := os.Pipe()
+			if err != nil {
+				r.startErr = err
+			} else {
+				r.cmd.Stdout = w
+				pw, next = w, pr
+			}
+		}
+		st.launch()
+		// 子进程已持有管道两端的副本，父进程需关闭自己的副本，否则下游读不到 EOF
+		if upstream != nil {
+			upstream.Close()
+		}
+		if pw != nil {
+			pw.Close()
+		}
+		upstream = next
+	}
+	for _, st := range stages {
+		st.Wait()
+	}
+
+	last := stages[len(stages)-1]
+	result.finishedAt = time.Now()
+	result.duration = result.finishedAt.Sub(result.startedAt)
+	result.attempts = 1
+	result.stdout, result.stdoutRaw = last.stdout, last.stdoutRaw
+	result.exitCode, result.state = 0, StateExited
+	var stderrs []string
+	for i, st := range stages {
+		if st.stderr != "" {
+			stderrs = append(stderrs, st.stderr)
+		}
+		if result.err != nil || !st.Failed() || (i < len(stages)-1 && brokenPipe(st)) {
+			continue
+		}
+		result.exitCode, result.state = st.exitCode, st.state
+		result.err = fmt.Errorf("mesh: pipeline stage %d (%s): %w", i+1, st.Cfg.Cmd, st.err)
+		result.stderr = fmt.Sprintf("stage %d (%s) exited with code %d", i+1, st.Cfg.Cmd, st.exitCode)
+		if st.stderr != "" {
+			result.stderr += ": " + st.stderr
+		}
+		result.stderrRaw = []byte(result.stderr)
+	}
+	if result.err == nil {
+		result.stderr = strings.Join(stderrs, "\n")
+		result.stderrRaw = []byte(result.stderr)
+	}
+	return result
+}
+
+// brokenPipe 判断进程是否因写入已关闭的管道被 SIGPIPE 终止，
+// Shell 会把子进程被信号终止转换为 128+信号值 的退出码
+func brokenPipe(t *Ts) bool {
+	if t.run == nil || t.run.cmd.ProcessState == nil {
+		return false
+	}
+	if t.exitCode == 128+int(syscall.SIGPIPE) {
+		return true
+	}
+	ws, ok := t.run.cmd.ProcessState.Sys().(syscall.WaitStatus)
+	return ok && ws.Signaled() && ws.Signal() == syscall.SIGPIPE
+}
@@ -0,0 +1,97 @@
+package mesh
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultCommandPolicy 是未设置 Config.CommandPolicy 时使用的包级命令策略，nil 表示不限制。
+// 应在程序初始化时设置，例如 AllowPrefixes("ls", "cat")
+var DefaultCommandPolicy func(cmd string) error
+
+// AllowCommand 设置启动进程前检查命令的策略（Shell 模式为 Cfg.Cmd，argv 模式为以空格连接的 Cfg.Args），fn 返回非 nil 时命令不会被执行，
+// Err 包装 ErrCommandDenied 与 fn 的错误，exitCode 为 -1，且不会重试
+func (t *Ts) AllowCommand(fn func(cmd string) error) *Ts {
+	t.Cfg.CommandPolicy = fn
+	return t
+}
+
+func (t *Ts) commandPolicy() func(cmd string) error {
+	if t.Cfg.CommandPolicy != nil {
+		return t.Cfg.CommandPolicy
+	}
+	return DefaultCommandPolicy
+}
+
+// checkExec 执行前检查命令策略与 ValidateOnExec，Exec 在选择缓存、熔断与重试路径之前调用，单独调用 Start 时由 prepare 调用
+func (t *Ts) checkExec() error {
+	if err := t.checkCommand(); err != nil {
+		return err
+	}
+	if t.Cfg.ValidateOnExec {
+		return t.Cfg.Validate()
+	}
+	return nil
+}
+
+// checkCommand 按策略检查实际要执行的命令。argv 模式下 Cmd 只用于展示，
+// 真正执行的是 Args，因此检查以空格连接的 Args，不能使用 Cmd
+func (t *Ts) checkCommand() error {
+	policy := t.commandPolicy()
+	if policy == nil {
+		return nil
+	}
+	cmd := t.Cfg.Cmd
+	if len(t.Cfg.Args) > 0 {
+		cmd = strings.Join(t.Cfg.Args, " ")
+	}
+	if err := policy(cmd); err != nil {
+		return fmt.Errorf("%w: %q: %w", ErrCommandDenied, cmd, err)
+	}
+	return nil
+}
+
+// shellMeta 是可以在一条命令后拼接其他命令或重定向的 Shell 元字符
+const shellMeta = ";&|`$<>()\n\r"
+
+// AllowPrefixes 返回只放行以某个前缀开头的命令的策略，前缀需完整匹配到单词边界
+// （"git status" 放行 "git status -s"，不放行 "git statusx"）。
+// 为避免 "ls; rm -rf /" 这类拼接绕过检查，含有 ; & | ` $ < > ( ) 或换行的命令一律拒绝
+func AllowPrefixes(prefixes ...string) func(cmd string) error {
+	return func(cmd string) error {
+		cmd = strings.TrimSpace(cmd)
+		if i := strings.IndexAny(cmd, shellMeta); i >= 0 {
+			return fmt.Errorf("shell metacharacter %q is not allowed", cmd[i])
+		}
+		for _, p := range prefixes {
+			if hasWordPrefix(cmd, p) {
+				return nil
+			}
+		}
+		return errors.New("not in allowlist")
+	}
+}
+
+// DenyPrefixes 返回拒绝以某个前缀开头的命令的策略，匹配规则与 AllowPrefixes 相同。
+// 拒绝名单只能拦截明确列出的写法，无法防止通过 Shell 拼接或别名绕过，不应作为唯一的安全边界
+func DenyPrefixes(prefixes ...string) func(cmd string) error {
+	return func(cmd string) error {
+		cmd = strings.TrimSpace(cmd)
+		for _, p := range prefixes {
+			if hasWordPrefix(cmd, p) {
+				return fmt.Errorf("matches denied prefix %q", p)
+			}
+		}
+		return nil
+	}
+}
+
+// hasWordPrefix 判断 s 是否等于 prefix 或以 prefix 加空白开头
+func hasWordPrefix(s, prefix string) bool {
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" || !strings.HasPrefix(s, prefix) {
+		return false
+	}
+	return len(s) == len(prefix) || s[len(prefix)] == ' ' || s[len(prefix)] == '\t'
+}
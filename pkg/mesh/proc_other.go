@@ -0,0 +1,57 @@
+//go:build !unix
+
+package mesh
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// setProcessGroup 在非 Unix 系统上不做处理，超时时直接终止子进程本身，grace 不生效
+func setProcessGroup(cmd *exec.Cmd, grace time.Duration) {}
+
+// setCredential 在非 Unix 系统上不支持切换用户
+func setCredential(cmd *exec.Cmd, name string) error {
+	return fmt.Errorf("%w: %s: not supported on this platform", ErrUserSwitch, name)
+}
+
+// setChroot 在非 Unix 系统上不支持 chroot
+func setChroot(cmd *exec.Cmd, root string) error {
+	return fmt.Errorf("%w: %s: not supported on this platform", ErrChroot, root)
+}
+
+// wrapRlimit 在非 Unix 系统上不设置资源限制
+func wrapRlimit(name string, args []string, cpu time.Duration, as uint64) (string, []string) {
+	return name, args
+}
+
+// cpuLimitKilled 在非 Unix 系统上始终返回 false
+func cpuLimitKilled(ps *os.ProcessState, limit time.Duration) bool {
+	return false
+}
+
+// setNice 在非 Unix 系统上不做处理
+func setNice(pid, nice int) error {
+	return nil
+}
+
+// startDetached 启动 cmd 后释放进程句柄，不等待也不回收
+func startDetached(cmd *exec.Cmd) (int, error) {
+	if cmd.Err != nil {
+		return 0, cmd.Err
+	}
+	c := exec.Command(cmd.Path)
+	c.Args = cmd.Args
+	c.Env = cmd.Env
+	c.Dir = cmd.Dir
+	c.ExtraFiles = cmd.ExtraFiles
+	c.SysProcAttr = cmd.SysProcAttr
+	if err := c.Start(); err != nil {
+		return 0, err
+	}
+	pid := c.Process.Pid
+	_ = c.Process.Release()
+	return pid, nil
+}
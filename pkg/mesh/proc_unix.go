@@ -0,0 +1,166 @@
+//go:build unix
+
+package mesh
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// setProcessGroup 让子进程成为新进程组的组长，context 结束时向整个进程组发送信号，
+// 避免 Shell 被终止后其派生的子进程成为孤儿继续运行。
+// grace 大于 0 时先发送 SIGTERM，等待 grace 后再发送 SIGKILL，否则直接 SIGKILL
+func setProcessGroup(cmd *exec.Cmd, grace time.Duration) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	cmd.Cancel = func() error {
+		if grace <= 0 {
+			return killGroup(cmd, syscall.SIGKILL)
+		}
+		time.AfterFunc(grace, func() { _ = killGroup(cmd, syscall.SIGKILL) })
+		return killGroup(cmd, syscall.SIGTERM)
+	}
+}
+
+// killGroup 向 cmd 所在的进程组发送信号
+func killGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return syscall.Kill(-cmd.Process.Pid, sig)
+}
+
+// setCredential 以 name（用户名或数字 uid）对应的 uid、gid 及附加组运行子进程。
+// 非 root 进程只能以自身身份运行，否则直接返回 ErrUserSwitch
+func setCredential(cmd *exec.Cmd, name string) error {
+	u, err := user.Lookup(name)
+	if _, atoiErr := strconv.Atoi(name); err != nil && atoiErr == nil {
+		u, err = user.LookupId(name)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUserSwitch, err)
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("%w: %s: invalid uid %q", ErrUserSwitch, name, u.Uid)
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("%w: %s: invalid gid %q", ErrUserSwitch, name, u.Gid)
+	}
+	if euid := os.Geteuid(); euid != 0 && uint64(euid) != uid {
+		return fmt.Errorf("%w: %s: current process (uid %d) lacks permission to switch users", ErrUserSwitch, name, euid)
+	}
+
+	var groups []uint32
+	if ids, err := u.GroupIds(); err == nil {
+		for _, id := range ids {
+			if g, err := strconv.ParseUint(id, 10, 32); err == nil {
+				groups = append(groups, uint32(g))
+			}
+		}
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid), Groups: groups}
+	return nil
+}
+
+// setChroot 让子进程以 root 为根目录运行，非 root 进程无法 chroot，直接返回 ErrChroot
+func setChroot(cmd *exec.Cmd, root string) error {
+	if euid := os.Geteuid(); euid != 0 {
+		return fmt.Errorf("%w: %s: current process (uid %d) is not root", ErrChroot, root, euid)
+	}
+	if info, err := os.Stat(root); err != nil {
+		return fmt.Errorf("%w: %w", ErrChroot, err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("%w: %s is not a directory", ErrChroot, root)
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Chroot = root
+	return nil
+}
+
+// wrapRlimit 通过 /bin/sh 的 ulimit 设置资源限制后再 exec 原命令，限制从进程第一条指令起生效，
+// 并由其派生的子进程继承。cpu 向上取整到秒，作为软限制，硬限制多 1 秒，
+// 使超限时先收到可识别的 SIGXCPU；as 向上取整到 KiB。为 0 表示不限制
+func wrapRlimit(name string, args []string, cpu time.Duration, as uint64) (string, []string) {
+	if cpu <= 0 && as == 0 {
+		return name, args
+	}
+	var script []string
+	if cpu > 0 {
+		secs := (cpu + time.Second - 1) / time.Second
+		script = append(script, fmt.Sprintf("ulimit -S -t %d", secs), fmt.Sprintf("ulimit -H -t %d", secs+1))
+	}
+	if as > 0 {
+		script = append(script, fmt.Sprintf("ulimit -v %d", (as+1023)/1024))
+	}
+	script = append(script, `exec "$@"`)
+	return "/bin/sh", append([]string{"-c", strings.Join(script, " && "), "mesh", name}, args...)
+}
+
+// cpuLimitKilled 判断进程是否因超过 CPU 时间限制被终止：软限制触发 SIGXCPU，
+// 进程忽略该信号时硬限制触发 SIGKILL；Shell 会把子进程被 SIGXCPU 终止转换为 128+信号值 的退出码
+func cpuLimitKilled(ps *os.ProcessState, limit time.Duration) bool {
+	ws, ok := ps.Sys().(syscall.WaitStatus)
+	if limit <= 0 || !ok {
+		return false
+	}
+	if !ws.Signaled() {
+		return ps.ExitCode() == 128+int(syscall.SIGXCPU)
+	}
+	switch ws.Signal() {
+	case syscall.SIGXCPU:
+		return true
+	case syscall.SIGKILL:
+		return ps.UserTime()+ps.SystemTime() >= limit
+	}
+	return false
+}
+
+// setNice 设置已启动进程的 nice 值，降低优先级（正值）无需特权，负值通常需要 root
+func setNice(pid, nice int) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, pid, nice)
+}
+
+// startDetached 在新会话中由 /bin/sh 将 cmd 放到后台启动并立即退出，cmd 随即被 init 收养，
+// 既不需要本进程回收，也不会在本进程存活期间成为僵尸进程；返回 cmd 的 PID
+func startDetached(cmd *exec.Cmd) (int, error) {
+	if cmd.Err != nil {
+		return 0, cmd.Err
+	}
+	args := append([]string{"-c", `"$@" </dev/null >/dev/null 2>&1 & echo $!`, "mesh", cmd.Path}, cmd.Args[1:]...)
+	sh := exec.Command("/bin/sh", args...)
+	sh.Env = cmd.Env
+	sh.Dir = cmd.Dir
+	sh.ExtraFiles = cmd.ExtraFiles
+	attr := syscall.SysProcAttr{}
+	if cmd.SysProcAttr != nil {
+		attr = *cmd.SysProcAttr
+	}
+	attr.Setpgid = false
+	attr.Setsid = true
+	sh.SysProcAttr = &attr
+	out, err := sh.Output()
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		return 0, fmt.Errorf("unexpected pid %q", out)
+	}
+	return pid, nil
+}
@@ -0,0 +1,22 @@
+//go:build !unix
+
+package mesh
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// ptyConn 在非 Unix 平台上不可用
+type ptyConn struct{}
+
+// openPTY 在非 Unix 平台上不受支持
+func openPTY(cmd *exec.Cmd) (*ptyConn, error) {
+	return nil, fmt.Errorf("mesh: pty is not supported on %s", runtime.GOOS)
+}
+
+func (p *ptyConn) start()                   {}
+func (p *ptyConn) wait(delay time.Duration) {}
+func (p *ptyConn) close()                   {}
@@ -0,0 +1,72 @@
+//go:build unix
+
+package mesh
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+)
+
+// ptyConn 持有伪终端的主端，子进程的输出由独立 goroutine 从主端拷贝到捕获链
+type ptyConn struct {
+	master *os.File
+	tty    *os.File
+	out    io.Writer
+	in     io.Reader
+	done   chan struct{}
+}
+
+// openPTY 为 cmd 分配伪终端，原先的 Stdout、Stdin 改为经由主端转发，stdin、stdout、stderr 均指向从端。
+// 子进程成为新会话的首进程并以从端为控制终端；会话首进程本身就是进程组组长，
+// 因此不能再设置 Setpgid，超时后终止整个进程组的逻辑保持不变
+func openPTY(cmd *exec.Cmd) (*ptyConn, error) {
+	master, tty, err := pty.Open()
+	if err != nil {
+		return nil, fmt.Errorf("mesh: open pty: %w", err)
+	}
+	p := &ptyConn{master: master, tty: tty, out: cmd.Stdout, in: cmd.Stdin, done: make(chan struct{})}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = tty, tty, tty
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = false
+	cmd.SysProcAttr.Setsid = true
+	cmd.SysProcAttr.Setctty = true
+	cmd.SysProcAttr.Ctty = 0
+	return p, nil
+}
+
+// start 在进程启动后关闭父进程持有的从端，并开始转发输入输出
+func (p *ptyConn) start() {
+	_ = p.tty.Close()
+	if p.in != nil {
+		go func() { _, _ = io.Copy(p.master, p.in) }()
+	}
+	go func() {
+		defer close(p.done)
+		// 从端全部关闭后读取主端返回 EIO，视为输出结束
+		_, _ = io.Copy(p.out, p.master)
+	}()
+}
+
+// wait 等待输出转发结束后关闭主端；后台子进程仍持有从端时最多等待 delay
+func (p *ptyConn) wait(delay time.Duration) {
+	select {
+	case <-p.done:
+	case <-time.After(delay):
+	}
+	_ = p.master.Close()
+	<-p.done
+}
+
+// close 在进程未能启动时释放伪终端
+func (p *ptyConn) close() {
+	_ = p.tty.Close()
+	_ = p.master.Close()
+}
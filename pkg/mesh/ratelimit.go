@@ -0,0 +1,20 @@
+package mesh
+
+import "golang.org/x/time/rate"
+
+// DefaultRateLimiter 是未设置 Config.RateLimiter 时使用的包级限流器，nil 表示不限流。
+// 应在程序初始化时设置，例如 rate.NewLimiter(rate.Every(100*time.Millisecond), 5)
+var DefaultRateLimiter *rate.Limiter
+
+// SetRateLimiter 设置启动进程前等待的限流器，多个 Ts 共用同一个限流器即可整体限速
+func (t *Ts) SetRateLimiter(l *rate.Limiter) *Ts {
+	t.Cfg.RateLimiter = l
+	return t
+}
+
+func (t *Ts) rateLimiter() *rate.Limiter {
+	if t.Cfg.RateLimiter != nil {
+		return t.Cfg.RateLimiter
+	}
+	return DefaultRateLimiter
+}
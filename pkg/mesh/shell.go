@@ -0,0 +1,104 @@
+package mesh
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// defaultShell 返回当前系统的默认 Shell，Windows 下为 cmd，其余为 bash
+func defaultShell() string {
+	if runtime.GOOS == "windows" {
+		return "cmd"
+	}
+	return "bash"
+}
+
+// fallbackShell 返回配置的 Shell 不可用时的回退 Shell
+func fallbackShell() string {
+	if runtime.GOOS == "windows" {
+		return "cmd"
+	}
+	return "sh"
+}
+
+// shellName 返回去掉路径与 .exe 后缀的小写 Shell 名称
+func shellName(shell string) string {
+	name := strings.ToLower(filepath.Base(shell))
+	return strings.TrimSuffix(name, ".exe")
+}
+
+// knownShells 是调用方式经过适配的 Shell：POSIX 系与 fish 从 stdin 或 -c 读取脚本，cmd 使用 /C，PowerShell 使用 -Command
+var knownShells = []string{"bash", "sh", "zsh", "fish", "powershell", "pwsh", "cmd"}
+
+// isKnownShell 判断 shell（可以带路径）是否属于 knownShells
+func isKnownShell(shell string) bool {
+	return slices.Contains(knownShells, shellName(shell))
+}
+
+// shellArgs 返回以 shell 执行 script 所需的参数，以及脚本是否通过 stdin 传入，extra 排在最前。
+// POSIX 系 Shell 默认从 stdin 读取脚本，needStdin 为 true 或 extra 以 -c 结尾时改用参数传入；
+// cmd 与 PowerShell 不适合从 stdin 读取脚本，始终以参数传入
+func shellArgs(shell string, extra []string, script string, needStdin bool) (args []string, viaStdin bool) {
+	args = slices.Clone(extra)
+	switch shellName(shell) {
+	case "cmd":
+		return append(args, "/D", "/S", "/C", script), false
+	case "powershell", "pwsh":
+		return append(args, "-NoLogo", "-NoProfile", "-NonInteractive", "-Command", script), false
+	default:
+		if len(args) > 0 && args[len(args)-1] == "-c" {
+			return append(args, script), false
+		}
+		if needStdin {
+			return append(args, "-c", script), false
+		}
+		return args, true
+	}
+}
+
+// scriptArgs 返回以 shell 执行脚本文件 path 的参数列表（含 shell 本身），extra 中结尾的 -c 会被忽略
+func scriptArgs(shell string, extra []string, path string) []string {
+	args := append([]string{shell}, extra...)
+	switch shellName(shell) {
+	case "cmd":
+		return append(args, "/D", "/C", path)
+	case "powershell", "pwsh":
+		return append(args, "-NoLogo", "-NoProfile", "-NonInteractive", "-File", path)
+	default:
+		if len(extra) > 0 && extra[len(extra)-1] == "-c" {
+			args = args[:len(args)-1]
+		}
+		return append(args, path)
+	}
+}
+
+// readShebang 读取脚本首行的 #! 解释器，按 Linux 的规则解释器后的其余内容作为单个参数。
+// 文件无法读取或没有 #! 时返回 nil，Windows 不支持 #!，同样返回 nil
+func readShebang(path string) []string {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	line, _ := bufio.NewReader(io.LimitReader(f, 256)).ReadString('\n')
+	line, ok := strings.CutPrefix(strings.TrimRight(line, "\r\n"), "#!")
+	if !ok {
+		return nil
+	}
+	interp, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	if interp == "" {
+		return nil
+	}
+	if arg = strings.TrimSpace(arg); arg != "" {
+		return []string{interp, arg}
+	}
+	return []string{interp}
+}
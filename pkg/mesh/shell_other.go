@@ -0,0 +1,8 @@
+//go:build !windows
+
+package mesh
+
+import "os/exec"
+
+// setCmdLine 仅在 Windows 下需要处理 cmd.exe 的命令行
+func setCmdLine(cmd *exec.Cmd, shell string, extra []string, script string) {}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package mesh
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setCmdLine 为 cmd.exe 直接设置原始命令行，cmd.exe 的引号规则与 Go 默认的参数转义不兼容
+func setCmdLine(cmd *exec.Cmd, shell string, extra []string, script string) {
+	if shellName(shell) != "cmd" {
+		return
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	// CmdLine 是完整命令行，需要包含程序本身
+	line := syscall.EscapeArg(cmd.Path)
+	for _, a := range extra {
+		line += " " + syscall.EscapeArg(a)
+	}
+	cmd.SysProcAttr.CmdLine = line + ` /D /S /C "` + script + `"`
+}
@@ -0,0 +1,50 @@
+package mesh
+
+import "fmt"
+
+// State 描述命令的执行状态，用于区分退出码同为 -1 的不同原因
+type State int
+
+const (
+	StatePending    State = iota // 尚未执行
+	StateRunning                 // 已启动，尚未 Wait
+	StateExited                  // 进程已退出，退出码可能非零
+	StateNotStarted              // 进程未能启动，如 Shell 缺失、工作目录无效
+	StateTimeout                 // 超过 Config.Timeout 被终止
+	StateCanceled                // 父 context 被取消而终止
+)
+
+func (s State) String() string {
+	switch s {
+	case StatePending:
+		return "pending"
+	case StateRunning:
+		return "running"
+	case StateExited:
+		return "exited"
+	case StateNotStarted:
+		return "notStarted"
+	case StateTimeout:
+		return "timeout"
+	case StateCanceled:
+		return "canceled"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText 以名称形式序列化状态，保证 JSON 中的取值稳定可读
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText 从名称解析状态
+func (s *State) UnmarshalText(text []byte) error {
+	for c := StatePending; c <= StateCanceled; c++ {
+		if c.String() == string(text) {
+			*s = c
+			return nil
+		}
+	}
+	return fmt.Errorf("mesh: unknown state %q", text)
+}
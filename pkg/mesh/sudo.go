@@ -0,0 +1,24 @@
+package mesh
+
+import (
+	"io"
+	"strings"
+)
+
+// sudoWrap 将命令包装为通过 sudo 执行。
+// 有密码时使用 -S -k：-k 忽略缓存的凭据，保证 sudo 总会先从 stdin 读取一行密码，
+// 剩余的 stdin 原样交给命令；-p ” 避免提示语混入 stderr。无密码时使用 -n，需要密码则立即失败
+func sudoWrap(password, name string, args []string, stdin io.Reader) (string, []string, io.Reader) {
+	sudoArgs := []string{"-n"}
+	if password != "" {
+		sudoArgs = []string{"-S", "-k", "-p", ""}
+		input := strings.NewReader(password + "\n")
+		if stdin != nil {
+			stdin = io.MultiReader(input, stdin)
+		} else {
+			stdin = input
+		}
+	}
+	sudoArgs = append(sudoArgs, "--", name)
+	return "sudo", append(sudoArgs, args...), stdin
+}
@@ -0,0 +1,69 @@
+package mesh
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Validate 检查配置中明显的错误并一次性返回所有问题，配置有效时返回 nil。
+// 返回的错误可通过 errors.Is 与 ErrInvalidConfig 匹配
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("timeout must not be negative (use 0 for no timeout), got %s", c.Timeout))
+	}
+	if c.Shell == "" && len(c.Args) == 0 {
+		errs = append(errs, errors.New("shell is empty and no args are set"))
+	}
+	if len(c.Args) > 0 && c.Args[0] == "" {
+		errs = append(errs, errors.New("args[0] (program name) is empty"))
+	}
+	if err := checkDir(c.workDir()); err != nil {
+		errs = append(errs, err)
+	}
+	for i, kv := range c.Env {
+		if k, _, ok := strings.Cut(kv, "="); !ok || k == "" {
+			errs = append(errs, fmt.Errorf("env[%d] %q is not in KEY=VALUE form", i, kv))
+		}
+	}
+	if _, err := lookupEncoding(c.Encoding); err != nil {
+		errs = append(errs, fmt.Errorf("unknown encoding %q", c.Encoding))
+	}
+	if c.Retries < 0 {
+		errs = append(errs, fmt.Errorf("retries must not be negative, got %d", c.Retries))
+	}
+	if c.RetryDelay < 0 {
+		errs = append(errs, fmt.Errorf("retry delay must not be negative, got %s", c.RetryDelay))
+	}
+	if c.IdleTimeout < 0 {
+		errs = append(errs, fmt.Errorf("idle timeout must not be negative, got %s", c.IdleTimeout))
+	}
+	if c.KillGracePeriod < 0 {
+		errs = append(errs, fmt.Errorf("kill grace period must not be negative, got %s", c.KillGracePeriod))
+	}
+	if c.MaxOutputBytes < 0 {
+		errs = append(errs, fmt.Errorf("max output bytes must not be negative, got %d", c.MaxOutputBytes))
+	}
+	for _, cpu := range c.CPUAffinity {
+		if cpu < 0 {
+			errs = append(errs, fmt.Errorf("cpu affinity must not be negative, got %d", cpu))
+		}
+	}
+	if c.RLimitCPU < 0 {
+		errs = append(errs, fmt.Errorf("rlimit cpu must not be negative, got %s", c.RLimitCPU))
+	}
+	if c.Nice < -20 || c.Nice > 19 {
+		errs = append(errs, fmt.Errorf("nice must be between -20 and 19, got %d", c.Nice))
+	}
+	if c.BreakerThreshold < 0 {
+		errs = append(errs, fmt.Errorf("breaker threshold must not be negative, got %d", c.BreakerThreshold))
+	}
+	if c.BreakerCooldown < 0 {
+		errs = append(errs, fmt.Errorf("breaker cooldown must not be negative, got %s", c.BreakerCooldown))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrInvalidConfig, errors.Join(errs...))
+}